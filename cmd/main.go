@@ -4,13 +4,31 @@ import (
 	"context"
 	"log"
 
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/metrics"
 	"ikurotime/backlog-go-backend/internal/server"
+	"ikurotime/backlog-go-backend/internal/tracing"
 	"ikurotime/backlog-go-backend/pkg/mongodbx"
 )
 
 func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Initialize tracing before anything creates spans
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracingConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize MongoDB client
-	client := mongodbx.ConnectMongoDB()
+	client := mongodbx.ConnectMongoDB(mongodbx.ChainMonitors(
+		tracing.CommandMonitor(),
+		metrics.CommandMonitor(),
+	))
 	defer client.Disconnect(context.Background())
 
 	// Create and start server
@@ -26,10 +26,24 @@ type Server struct {
 	AllowedOrigin string `yaml:"allowedOrigin"`
 }
 
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	ServiceName string  `yaml:"serviceName"`
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+type TrashConfig struct {
+	RetentionDays int `yaml:"retentionDays"`
+}
+
 type Config struct {
 	Server        Server        `yaml:"server"`
 	MongoDBConfig MongoDBConfig `yaml:"mongodb"`
 	ClerkConfig   ClerkConfig   `yaml:"clerk"`
+	TracingConfig TracingConfig `yaml:"tracing"`
+	TrashConfig   TrashConfig   `yaml:"trash"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,111 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind classifies an error and determines its HTTP status
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindValidation
+	KindConflict
+	KindUnauthorized
+	KindForbidden
+	KindRateLimited
+)
+
+// Status returns the HTTP status code for the kind
+func (k Kind) Status() int {
+	switch k {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error carrying a stable machine-readable code.
+// Message is safe to show to clients; Err is only logged.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound reports a missing resource
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Validation reports invalid input, optionally per field
+func Validation(code, message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+// Conflict reports a request that clashes with the current state
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Unauthorized reports missing or invalid credentials
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Forbidden reports an authenticated caller lacking permission
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+// RateLimited reports a caller exceeding a rate limit
+func RateLimited(code, message string) *Error {
+	return &Error{Kind: KindRateLimited, Code: code, Message: message}
+}
+
+// Internal wraps an unexpected failure; err is logged but never rendered
+func Internal(code, message string, err error) *Error {
+	return &Error{Kind: KindInternal, Code: code, Message: message, Err: err}
+}
+
+// From converts any error into an *Error, treating unknown errors as internal
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("internal_error", "An unexpected error occurred", err)
+}
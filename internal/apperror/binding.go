@@ -0,0 +1,74 @@
+package apperror
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report field errors using JSON names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// FromBinding converts a gin binding error into a validation error with
+// one FieldError per failed constraint
+func FromBinding(err error) *Error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return Validation("invalid_body", "Request body is not valid JSON")
+	}
+
+	fields := make([]FieldError, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, FieldError{
+			Field:   fieldPath(fe),
+			Message: describe(fe),
+		})
+	}
+	return Validation("invalid_body", "Request body failed validation", fields...)
+}
+
+// fieldPath drops the top-level struct name from the namespace
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+func describe(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + fe.Param()
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "len":
+		return "must have length " + fe.Param()
+	case "hexadecimal":
+		return "must be hexadecimal"
+	default:
+		return "failed " + fe.Tag() + " validation"
+	}
+}
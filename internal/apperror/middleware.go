@@ -0,0 +1,62 @@
+package apperror
+
+import (
+	"ikurotime/backlog-go-backend/internal/requestid"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details document
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// Abort records err on the context and stops the handler chain.
+// The response itself is written by Middleware.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Middleware renders the last error recorded on the context as problem+json
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := From(c.Errors.Last().Err)
+		status := appErr.Kind.Status()
+		if status >= http.StatusInternalServerError {
+			log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, appErr)
+		}
+
+		problem := Problem{
+			Type:      "urn:backlogg:problem:" + appErr.Code,
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    appErr.Message,
+			Instance:  c.Request.URL.Path,
+			Code:      appErr.Code,
+			RequestID: requestid.Get(c),
+			Errors:    appErr.Fields,
+		}
+
+		// gin keeps an explicitly set Content-Type when rendering JSON
+		c.Header("Content-Type", problemContentType)
+		c.JSON(status, problem)
+	}
+}
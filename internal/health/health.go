@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckFunc reports whether a dependency is usable
+type CheckFunc func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Checker runs readiness checks and tracks whether the process is shutting down
+type Checker struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker that gives each check at most timeout to complete
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a named readiness check
+func (h *Checker) Register(name string, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// SetShuttingDown makes readiness fail so load balancers stop routing traffic
+func (h *Checker) SetShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// Live reports that the process is up and serving requests
+func (h *Checker) Live() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// Ready runs every registered check concurrently and returns 503 if any fails
+func (h *Checker) Ready() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.shuttingDown.Load() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down"})
+			return
+		}
+
+		h.mu.RLock()
+		checks := make([]namedCheck, len(h.checks))
+		copy(checks, h.checks)
+		h.mu.RUnlock()
+
+		results := make(map[string]CheckResult, len(checks))
+		var resultsMu sync.Mutex
+		var wg sync.WaitGroup
+		for _, nc := range checks {
+			wg.Add(1)
+			go func(nc namedCheck) {
+				defer wg.Done()
+				result := h.run(c.Request.Context(), nc.check)
+				resultsMu.Lock()
+				results[nc.name] = result
+				resultsMu.Unlock()
+			}(nc)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		overall := "ok"
+		for _, result := range results {
+			if result.Status != "ok" {
+				status = http.StatusServiceUnavailable
+				overall = "unavailable"
+				break
+			}
+		}
+
+		c.JSON(status, gin.H{
+			"status": overall,
+			"checks": results,
+		})
+	}
+}
+
+func (h *Checker) run(ctx context.Context, check CheckFunc) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "fail"
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,399 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Progress statuses of a build
+const (
+	BuildPlanning  = "planning"
+	BuildBuilding  = "building"
+	BuildShipped   = "shipped"
+	BuildAbandoned = "abandoned"
+)
+
+// BuilderCounts is the number of builders of an idea in each build status
+type BuilderCounts struct {
+	Planning  int `bson:"planning" json:"planning"`
+	Building  int `bson:"building" json:"building"`
+	Shipped   int `bson:"shipped" json:"shipped"`
+	Abandoned int `bson:"abandoned" json:"abandoned"`
+}
+
+// Showcase is what a builder shipped
+type Showcase struct {
+	RepoURL string `bson:"repo_url,omitempty" json:"repo_url,omitempty" binding:"omitempty,url,max=2048"`
+	DemoURL string `bson:"demo_url,omitempty" json:"demo_url,omitempty" binding:"omitempty,url,max=2048"`
+	WriteUp string `bson:"write_up,omitempty" json:"write_up,omitempty" binding:"max=20000"`
+}
+
+// Build is a user's claim on an idea and their progress building it,
+// stored in the idea_builds collection
+type Build struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	IdeaID    bson.ObjectID `bson:"idea_id" json:"idea_id"`
+	UserID    string        `bson:"user_id" json:"user_id"`
+	Status    string        `bson:"status" json:"status"`
+	Showcase  *Showcase     `bson:"showcase,omitempty" json:"showcase,omitempty"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+	ShippedAt *time.Time    `bson:"shipped_at,omitempty" json:"shipped_at,omitempty"`
+}
+
+type claimIdeaRequest struct {
+	Status string `json:"status" binding:"omitempty,oneof=planning building"`
+}
+
+type updateBuildRequest struct {
+	Status   string    `json:"status" binding:"required,oneof=planning building shipped abandoned"`
+	Showcase *Showcase `json:"showcase"`
+}
+
+// builderCountField is the idea field counting builders in status
+func builderCountField(status string) string {
+	return "builder_counts." + status
+}
+
+// findClaimableIdea checks the idea exists, is published and isn't in the trash
+func findClaimableIdea(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID) error {
+	n, err := db.Collection("ideas").CountDocuments(ctx, Live(bson.M{"_id": ideaID, "status": listedStatuses()}))
+	if err != nil {
+		return apperror.Internal("check_idea_failed", "Failed to check idea", err)
+	}
+	if n == 0 {
+		return apperror.NotFound("idea_not_found", "Idea not found")
+	}
+	return nil
+}
+
+// ClaimIdea records that the caller is building an idea
+func (h *Handler) ClaimIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var req claimIdeaRequest
+	// The body is optional; an empty one claims the idea as planning
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperror.Abort(c, apperror.FromBinding(err))
+			return
+		}
+	}
+	if req.Status == "" {
+		req.Status = BuildPlanning
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if err := findClaimableIdea(ctx, db, ideaID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	now := time.Now()
+	build := Build{
+		ID:        bson.NewObjectID(),
+		IdeaID:    ideaID,
+		UserID:    userID,
+		Status:    req.Status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		// Upsert against the unique idea_id+user_id index so a user can only
+		// claim an idea once
+		result, err := db.Collection("idea_builds").UpdateOne(sessCtx,
+			bson.M{"idea_id": ideaID, "user_id": userID},
+			bson.M{"$setOnInsert": build},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if result.UpsertedCount == 0 {
+			return nil, apperror.Conflict("already_claimed", "You have already claimed this idea")
+		}
+
+		_, err = db.Collection("ideas").UpdateOne(sessCtx,
+			bson.M{"_id": ideaID},
+			bson.M{"$inc": bson.M{builderCountField(build.Status): 1}},
+		)
+		return nil, err
+	})
+	if err != nil {
+		abortBuildError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": build})
+}
+
+// UpdateBuild changes the caller's progress on an idea they claimed. A
+// showcase can only be attached once the build has shipped.
+func (h *Handler) UpdateBuild(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var req updateBuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	if req.Showcase != nil {
+		if req.Status != BuildShipped {
+			apperror.Abort(c, apperror.Validation("showcase_requires_shipped", "A showcase can only be added to a shipped build", apperror.FieldError{
+				Field:   "showcase",
+				Message: "requires status shipped",
+			}))
+			return
+		}
+		if req.Showcase.RepoURL == "" && req.Showcase.DemoURL == "" {
+			apperror.Abort(c, apperror.Validation("invalid_showcase", "A showcase needs a repository or demo URL", apperror.FieldError{
+				Field:   "showcase.repo_url",
+				Message: "repo_url or demo_url is required",
+			}))
+			return
+		}
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	builds := db.Collection("idea_builds")
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	var updated Build
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		filter := bson.M{"idea_id": ideaID, "user_id": userID}
+
+		var current Build
+		err := builds.FindOne(sessCtx, filter).Decode(&current)
+		if err == mongo.ErrNoDocuments {
+			return nil, apperror.NotFound("build_not_found", "You have not claimed this idea")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		set := bson.M{"status": req.Status, "updated_at": now}
+		update := bson.M{"$set": set}
+		switch {
+		case req.Status != BuildShipped:
+			update["$unset"] = bson.M{"showcase": "", "shipped_at": ""}
+		case req.Showcase != nil:
+			set["showcase"] = req.Showcase
+		}
+		if req.Status == BuildShipped && current.ShippedAt == nil {
+			set["shipped_at"] = now
+		}
+
+		// Matching on the current status keeps builder counts right if the
+		// build changed since it was read
+		filter["status"] = current.Status
+		err = builds.FindOneAndUpdate(sessCtx, filter, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err != nil {
+			return nil, err
+		}
+
+		if current.Status == req.Status {
+			return nil, nil
+		}
+		_, err = db.Collection("ideas").UpdateOne(sessCtx,
+			bson.M{"_id": ideaID},
+			bson.M{"$inc": bson.M{
+				builderCountField(current.Status): -1,
+				builderCountField(req.Status):     1,
+			}},
+		)
+		return nil, err
+	})
+	if err != nil {
+		abortBuildError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// UnclaimIdea removes the caller's claim on an idea
+func (h *Handler) UnclaimIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		var removed Build
+		err := db.Collection("idea_builds").FindOneAndDelete(sessCtx,
+			bson.M{"idea_id": ideaID, "user_id": userID},
+		).Decode(&removed)
+		if err == mongo.ErrNoDocuments {
+			return nil, apperror.NotFound("build_not_found", "You have not claimed this idea")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = db.Collection("ideas").UpdateOne(sessCtx,
+			bson.M{"_id": ideaID},
+			bson.M{"$inc": bson.M{builderCountField(removed.Status): -1}},
+		)
+		return nil, err
+	})
+	if err != nil {
+		abortBuildError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Idea unclaimed successfully"})
+}
+
+// GetBuilds lists the builders of an idea, most recently updated first,
+// optionally filtered by status
+func (h *Handler) GetBuilds(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	filter := bson.M{"idea_id": ideaID}
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case BuildPlanning, BuildBuilding, BuildShipped, BuildAbandoned:
+			filter["status"] = status
+		default:
+			apperror.Abort(c, apperror.Validation("invalid_status", "Invalid build status", apperror.FieldError{
+				Field:   "status",
+				Message: "must be one of planning, building, shipped, abandoned",
+			}))
+			return
+		}
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if _, err := findVisibleIdea(ctx, c, db, ideaID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	collection := db.Collection("idea_builds")
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_builds_failed", "Failed to count builds", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_builds_failed", "Failed to fetch builds", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var builds []Build
+	if err := cursor.All(ctx, &builds); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_builds_failed", "Failed to decode builds", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       builds,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// abortBuildError aborts with err, wrapping unexpected errors as internal
+func abortBuildError(c *gin.Context, err error) {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		err = apperror.Internal("update_build_failed", "Failed to update build", err)
+	}
+	apperror.Abort(c, err)
+}
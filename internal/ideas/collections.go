@@ -0,0 +1,478 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/metrics"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Collection is a named folder a user files bookmarks into
+type Collection struct {
+	ID             bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         string        `bson:"user_id" json:"user_id"`
+	Name           string        `bson:"name" json:"name"`
+	Position       int           `bson:"position" json:"position"`
+	BookmarksCount int           `bson:"-" json:"bookmarks_count"`
+	CreatedAt      time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+type collectionRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+type reorderCollectionsRequest struct {
+	CollectionIDs []string `json:"collection_ids" binding:"required"`
+}
+
+type fileBookmarkRequest struct {
+	CollectionIDs []string `json:"collection_ids"`
+	Note          string   `json:"note" binding:"max=2000"`
+}
+
+func errInvalidCollectionID() *apperror.Error {
+	return apperror.Validation("invalid_collection_id", "Invalid collection ID", apperror.FieldError{
+		Field:   "id",
+		Message: "must be a 24 character hex ObjectID",
+	})
+}
+
+func errCollectionNameTaken() *apperror.Error {
+	return apperror.Conflict("collection_name_taken", "A collection with this name already exists")
+}
+
+// parseObjectIDs converts hex IDs, reporting the first invalid one under field
+func parseObjectIDs(field string, ids []string) ([]bson.ObjectID, error) {
+	seen := make(map[bson.ObjectID]bool, len(ids))
+	out := make([]bson.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, apperror.Validation("invalid_collection_id", "Invalid collection ID", apperror.FieldError{
+				Field:   field,
+				Message: id + " is not a valid ObjectID",
+			})
+		}
+		if !seen[oid] {
+			seen[oid] = true
+			out = append(out, oid)
+		}
+	}
+	return out, nil
+}
+
+// ownsCollections reports whether every ID refers to one of the user's collections
+func ownsCollections(ctx context.Context, db *mongo.Database, userID string, ids []bson.ObjectID) (bool, error) {
+	if len(ids) == 0 {
+		return true, nil
+	}
+	n, err := db.Collection("bookmark_collections").CountDocuments(ctx, bson.M{
+		"_id":     bson.M{"$in": ids},
+		"user_id": userID,
+	})
+	return n == int64(len(ids)), err
+}
+
+// ListCollections returns the caller's collections in display order with bookmark counts
+func (h *Handler) ListCollections(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := db.Collection("bookmark_collections").Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_collections_failed", "Failed to fetch collections", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	collections := []Collection{}
+	if err := cursor.All(ctx, &collections); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_collections_failed", "Failed to decode collections", err))
+		return
+	}
+
+	countCursor, err := db.Collection("bookmarks").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "collection_ids.0": bson.M{"$exists": true}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "ideas",
+			"localField":   "idea_id",
+			"foreignField": "_id",
+			"as":           "idea",
+		}}},
+		{{Key: "$match", Value: bson.M{"idea": bson.M{"$elemMatch": bson.M{
+			"status":     bson.M{"$ne": StatusDraft},
+			"deleted_at": bson.M{"$exists": false},
+		}}}}},
+		{{Key: "$unwind", Value: "$collection_ids"}},
+		{{Key: "$group", Value: bson.M{"_id": "$collection_ids", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_bookmarks_failed", "Failed to count bookmarks", err))
+		return
+	}
+	defer countCursor.Close(ctx)
+
+	var counts []struct {
+		ID    bson.ObjectID `bson:"_id"`
+		Count int           `bson:"count"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		apperror.Abort(c, apperror.Internal("count_bookmarks_failed", "Failed to count bookmarks", err))
+		return
+	}
+
+	byID := make(map[bson.ObjectID]int, len(counts))
+	for _, cnt := range counts {
+		byID[cnt.ID] = cnt.Count
+	}
+	for i := range collections {
+		collections[i].BookmarksCount = byID[collections[i].ID]
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collections})
+}
+
+// CreateCollection creates a named collection at the end of the caller's list
+func (h *Handler) CreateCollection(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req collectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	coll := db.Collection("bookmark_collections")
+
+	// Place it after the last collection; counting would reuse a position
+	// freed by a deleted collection
+	position := 0
+	var last Collection
+	err = coll.FindOne(ctx,
+		bson.M{"user_id": userID},
+		options.FindOne().SetSort(bson.D{{Key: "position", Value: -1}}).SetProjection(bson.M{"position": 1}),
+	).Decode(&last)
+	if err == nil {
+		position = last.Position + 1
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		apperror.Abort(c, apperror.Internal("create_collection_failed", "Failed to create collection", err))
+		return
+	}
+
+	now := time.Now()
+	collection := Collection{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		Position:  position,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	result, err := coll.InsertOne(ctx, collection)
+	if mongo.IsDuplicateKeyError(err) {
+		apperror.Abort(c, errCollectionNameTaken())
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("create_collection_failed", "Failed to create collection", err))
+		return
+	}
+	collection.ID = result.InsertedID.(bson.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{"data": collection})
+}
+
+// RenameCollection changes the name of one of the caller's collections
+func (h *Handler) RenameCollection(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	collectionID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidCollectionID())
+		return
+	}
+
+	var req collectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	var collection Collection
+	err = db.Collection("bookmark_collections").FindOneAndUpdate(ctx,
+		bson.M{"_id": collectionID, "user_id": userID},
+		bson.M{"$set": bson.M{"name": strings.TrimSpace(req.Name), "updated_at": time.Now()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&collection)
+	if mongo.IsDuplicateKeyError(err) {
+		apperror.Abort(c, errCollectionNameTaken())
+		return
+	}
+	if err == mongo.ErrNoDocuments {
+		apperror.Abort(c, apperror.NotFound("collection_not_found", "Collection not found"))
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("rename_collection_failed", "Failed to rename collection", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collection})
+}
+
+// ReorderCollections sets the display order of the caller's collections.
+// The request must list every collection exactly once.
+func (h *Handler) ReorderCollections(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req reorderCollectionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	ids, err := parseObjectIDs("collection_ids", req.CollectionIDs)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	coll := db.Collection("bookmark_collections")
+
+	total, err := coll.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("reorder_collections_failed", "Failed to reorder collections", err))
+		return
+	}
+	owned, err := ownsCollections(ctx, db, userID, ids)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("reorder_collections_failed", "Failed to reorder collections", err))
+		return
+	}
+	if !owned || int64(len(ids)) != total || len(ids) != len(req.CollectionIDs) {
+		apperror.Abort(c, apperror.Validation("invalid_collection_order", "collection_ids must list each of your collections exactly once", apperror.FieldError{
+			Field:   "collection_ids",
+			Message: "must contain every collection ID exactly once",
+		}))
+		return
+	}
+
+	if len(ids) > 0 {
+		now := time.Now()
+		models := make([]mongo.WriteModel, 0, len(ids))
+		for i, id := range ids {
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": id, "user_id": userID}).
+				SetUpdate(bson.M{"$set": bson.M{"position": i, "updated_at": now}}))
+		}
+		if _, err := coll.BulkWrite(ctx, models); err != nil {
+			apperror.Abort(c, apperror.Internal("reorder_collections_failed", "Failed to reorder collections", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collections reordered successfully"})
+}
+
+// DeleteCollection removes a collection. Bookmarks filed in it are kept.
+func (h *Handler) DeleteCollection(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	collectionID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidCollectionID())
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	result, err := db.Collection("bookmark_collections").DeleteOne(ctx, bson.M{
+		"_id":     collectionID,
+		"user_id": userID,
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("delete_collection_failed", "Failed to delete collection", err))
+		return
+	}
+	if result.DeletedCount == 0 {
+		apperror.Abort(c, apperror.NotFound("collection_not_found", "Collection not found"))
+		return
+	}
+
+	_, err = db.Collection("bookmarks").UpdateMany(ctx,
+		bson.M{"user_id": userID, "collection_ids": collectionID},
+		bson.M{"$pull": bson.M{"collection_ids": collectionID}},
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("delete_collection_failed", "Failed to delete collection", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+}
+
+// GetCollectionIdeas lists the bookmarked ideas filed in one of the caller's collections
+func (h *Handler) GetCollectionIdeas(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	collectionID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidCollectionID())
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	owned, err := ownsCollections(ctx, db, userID, []bson.ObjectID{collectionID})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_collection_failed", "Failed to fetch collection", err))
+		return
+	}
+	if !owned {
+		apperror.Abort(c, apperror.NotFound("collection_not_found", "Collection not found"))
+		return
+	}
+
+	h.listBookmarkedIdeas(ctx, c, db, bson.M{"user_id": userID, "collection_ids": collectionID})
+}
+
+// FileBookmark sets the collections and private note of the caller's bookmark
+// on an idea, bookmarking it first if needed
+func (h *Handler) FileBookmark(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var req fileBookmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	collectionIDs, err := parseObjectIDs("collection_ids", req.CollectionIDs)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	exists, err := db.Collection("ideas").CountDocuments(ctx, Live(bson.M{"_id": ideaID, "status": bson.M{"$ne": StatusDraft}}))
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("file_bookmark_failed", "Failed to file bookmark", err))
+		return
+	}
+	if exists == 0 {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
+		return
+	}
+
+	owned, err := ownsCollections(ctx, db, userID, collectionIDs)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("file_bookmark_failed", "Failed to file bookmark", err))
+		return
+	}
+	if !owned {
+		apperror.Abort(c, apperror.NotFound("collection_not_found", "Collection not found"))
+		return
+	}
+
+	bookmarks := db.Collection("bookmarks")
+	filter := bson.M{"user_id": userID, "idea_id": ideaID}
+	result, err := bookmarks.UpdateOne(ctx,
+		filter,
+		bson.M{
+			"$set":         bson.M{"collection_ids": collectionIDs, "note": strings.TrimSpace(req.Note)},
+			"$setOnInsert": bson.M{"created_at": time.Now()},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("file_bookmark_failed", "Failed to file bookmark", err))
+		return
+	}
+	if result.UpsertedCount > 0 {
+		metrics.Bookmarks.WithLabelValues("bookmark").Inc()
+	}
+
+	var bookmark Bookmark
+	if err := bookmarks.FindOne(ctx, filter).Decode(&bookmark); err != nil {
+		apperror.Abort(c, apperror.Internal("file_bookmark_failed", "Failed to file bookmark", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": bookmark})
+}
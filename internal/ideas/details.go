@@ -0,0 +1,160 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Milestone is a step towards completing an idea
+type Milestone struct {
+	Title       string `bson:"title" json:"title" binding:"required,max=200"`
+	Description string `bson:"description,omitempty" json:"description,omitempty" binding:"max=2000"`
+}
+
+// ResourceLink points to material that helps build an idea
+type ResourceLink struct {
+	Title string `bson:"title" json:"title" binding:"required,max=200"`
+	URL   string `bson:"url" json:"url" binding:"required,url,max=2048"`
+}
+
+// IdeaDetails holds the structured, author-maintained details of an idea,
+// stored in the idea_details collection
+type IdeaDetails struct {
+	Requirements     []string       `bson:"requirements" json:"requirements" binding:"max=50,dive,required,max=500"`
+	TechStack        []string       `bson:"tech_stack" json:"tech_stack" binding:"max=30,dive,required,max=100"`
+	Milestones       []Milestone    `bson:"milestones" json:"milestones" binding:"max=30,dive"`
+	LearningOutcomes []string       `bson:"learning_outcomes" json:"learning_outcomes" binding:"max=30,dive,required,max=500"`
+	EstimatedHours   int            `bson:"estimated_hours" json:"estimated_hours" binding:"min=0,max=10000"`
+	Resources        []ResourceLink `bson:"resources" json:"resources" binding:"max=50,dive"`
+	UpdatedAt        time.Time      `bson:"updated_at" json:"updated_at" binding:"-"`
+}
+
+// findAuthoredIdea loads an idea and checks the caller is its author or an admin
+func findAuthoredIdea(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID, userID string, admin bool) (*Idea, error) {
+	var idea Idea
+	err := db.Collection("ideas").FindOne(ctx, Live(bson.M{"_id": ideaID})).Decode(&idea)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("idea_not_found", "Idea not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_idea_failed", "Failed to fetch idea", err)
+	}
+	if idea.AuthorID != userID && !admin {
+		return nil, apperror.Forbidden("not_idea_author", "Only the idea author can change this idea")
+	}
+	return &idea, nil
+}
+
+// normalizeDetails replaces nil slices so clients always get arrays
+func normalizeDetails(d *IdeaDetails) {
+	if d.Requirements == nil {
+		d.Requirements = []string{}
+	}
+	if d.TechStack == nil {
+		d.TechStack = []string{}
+	}
+	if d.Milestones == nil {
+		d.Milestones = []Milestone{}
+	}
+	if d.LearningOutcomes == nil {
+		d.LearningOutcomes = []string{}
+	}
+	if d.Resources == nil {
+		d.Resources = []ResourceLink{}
+	}
+}
+
+// CreateDetails adds structured details to an idea the caller authored
+func (h *Handler) CreateDetails(c *gin.Context) {
+	h.writeDetails(c, false)
+}
+
+// UpdateDetails replaces the structured details of an idea the caller authored
+func (h *Handler) UpdateDetails(c *gin.Context) {
+	h.writeDetails(c, true)
+}
+
+func (h *Handler) writeDetails(c *gin.Context, replace bool) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var details IdeaDetails
+	if err := c.ShouldBindJSON(&details); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	normalizeDetails(&details)
+	details.UpdatedAt = time.Now()
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	idea, err := findAuthoredIdea(ctx, db, ideaID, userID, isAdmin(c))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	coll := db.Collection("idea_details")
+	status := http.StatusOK
+	_, err = recordEdit(ctx, db, idea, userID, nil, func() error {
+		if replace {
+			result, err := coll.UpdateOne(ctx, bson.M{"idea_id": ideaID}, bson.M{"$set": details})
+			if err != nil {
+				return err
+			}
+			if result.MatchedCount == 0 {
+				return apperror.NotFound("details_not_found", "Idea has no details yet")
+			}
+		} else {
+			result, err := coll.UpdateOne(ctx,
+				bson.M{"idea_id": ideaID},
+				bson.M{"$setOnInsert": details},
+				options.UpdateOne().SetUpsert(true),
+			)
+			if err != nil {
+				return err
+			}
+			if result.UpsertedCount == 0 {
+				return apperror.Conflict("details_exist", "Idea already has details")
+			}
+			status = http.StatusCreated
+		}
+
+		_, err := db.Collection("ideas").UpdateOne(ctx,
+			bson.M{"_id": ideaID},
+			bson.M{"$set": bson.M{"updated_at": details.UpdatedAt}},
+		)
+		return err
+	})
+	if err != nil {
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) {
+			err = apperror.Internal("write_details_failed", "Failed to save idea details", err)
+		}
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(status, gin.H{"data": details})
+}
@@ -0,0 +1,377 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/similarity"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// duplicateCandidates is how many text search matches are rescored locally
+	duplicateCandidates = 50
+	// maxDuplicates caps how many likely duplicates are returned
+	maxDuplicates = 5
+	// duplicateThreshold is the lowest similarity reported as a likely duplicate
+	duplicateThreshold = 0.25
+	// maxSearchText caps how much text is sent to the text index
+	maxSearchText = 1000
+)
+
+// DuplicateCandidate is an existing idea that looks like the one being submitted
+type DuplicateCandidate struct {
+	ID        bson.ObjectID `json:"id"`
+	Title     string        `json:"title"`
+	AuthorID  string        `json:"author_id"`
+	Score     float64       `json:"score"`
+	TextScore float64       `json:"text_score"`
+}
+
+type checkDuplicatesRequest struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=10000"`
+	ExcludeID   string `json:"exclude_id" binding:"omitempty,len=24,hexadecimal"`
+}
+
+type mergeIdeaRequest struct {
+	Into string `json:"into" binding:"required,len=24,hexadecimal"`
+}
+
+// findDuplicates narrows published ideas down with the text_search index and
+// rescores the matches by shingle similarity of title and description
+func findDuplicates(ctx context.Context, db *mongo.Database, title, description string, exclude bson.ObjectID) ([]DuplicateCandidate, error) {
+	search := title + " " + description
+	if runes := []rune(search); len(runes) > maxSearchText {
+		search = string(runes[:maxSearchText])
+	}
+
+	filter := Live(bson.M{
+		"$text":  bson.M{"$search": search},
+		"status": listedStatuses(),
+	})
+	if !exclude.IsZero() {
+		filter["_id"] = bson.M{"$ne": exclude}
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{
+			"title":       1,
+			"description": 1,
+			"author_id":   1,
+			"text_score":  bson.M{"$meta": "textScore"},
+		}).
+		SetSort(bson.M{"text_score": bson.M{"$meta": "textScore"}}).
+		SetLimit(duplicateCandidates)
+
+	cursor, err := db.Collection("ideas").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var matches []struct {
+		ID          bson.ObjectID `bson:"_id"`
+		Title       string        `bson:"title"`
+		Description string        `bson:"description"`
+		AuthorID    string        `bson:"author_id"`
+		TextScore   float64       `bson:"text_score"`
+	}
+	if err := cursor.All(ctx, &matches); err != nil {
+		return nil, err
+	}
+
+	submitted := similarity.NewFingerprint(title, description)
+	candidates := []DuplicateCandidate{}
+	for _, m := range matches {
+		score := submitted.Score(similarity.NewFingerprint(m.Title, m.Description))
+		if score < duplicateThreshold {
+			continue
+		}
+		candidates = append(candidates, DuplicateCandidate{
+			ID:        m.ID,
+			Title:     m.Title,
+			AuthorID:  m.AuthorID,
+			Score:     score,
+			TextScore: m.TextScore,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > maxDuplicates {
+		candidates = candidates[:maxDuplicates]
+	}
+	return candidates, nil
+}
+
+// duplicatesOf returns likely duplicates of idea for submission responses.
+// Failures are logged rather than failing the submission.
+func duplicatesOf(ctx context.Context, db *mongo.Database, idea *Idea) []DuplicateCandidate {
+	candidates, err := findDuplicates(ctx, db, idea.Title, idea.Description, idea.ID)
+	if err != nil {
+		log.Printf("Failed to check idea %s for duplicates: %v", idea.ID.Hex(), err)
+		return []DuplicateCandidate{}
+	}
+	return candidates
+}
+
+// CheckDuplicates returns published ideas similar to a title and description
+// so clients can warn authors before they submit
+func (h *Handler) CheckDuplicates(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req checkDuplicatesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	var exclude bson.ObjectID
+	if req.ExcludeID != "" {
+		exclude, _ = bson.ObjectIDFromHex(req.ExcludeID)
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	candidates, err := findDuplicates(ctx, db, req.Title, req.Description, exclude)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("check_duplicates_failed", "Failed to check for duplicates", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": candidates})
+}
+
+// MergeIdea folds a duplicate idea into the surviving idea given in the body.
+// Likes, comments and bookmarks move to the survivor and the duplicate is
+// moved to the trash. Only moderators (admins) can merge.
+func (h *Handler) MergeIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 30*time.Second)
+	defer cancel()
+
+	if !isAdmin(c) {
+		apperror.Abort(c, apperror.Forbidden("not_moderator", "Only moderators can merge ideas"))
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	duplicateID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var req mergeIdeaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	survivorID, _ := bson.ObjectIDFromHex(req.Into)
+	if survivorID == duplicateID {
+		apperror.Abort(c, apperror.Validation("merge_into_self", "An idea can't be merged into itself", apperror.FieldError{
+			Field:   "into",
+			Message: "must be a different idea",
+		}))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	ideasColl := db.Collection("ideas")
+	var survivorStatus string
+	for _, id := range []bson.ObjectID{duplicateID, survivorID} {
+		var idea Idea
+		err := ideasColl.FindOne(ctx,
+			Live(bson.M{"_id": id}),
+			options.FindOne().SetProjection(bson.M{"status": 1}),
+		).Decode(&idea)
+		if err == mongo.ErrNoDocuments {
+			apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
+			return
+		}
+		if err != nil {
+			apperror.Abort(c, apperror.Internal("check_idea_failed", "Failed to check idea", err))
+			return
+		}
+		if id == survivorID {
+			survivorStatus = idea.Status
+		}
+	}
+	// The engagement moved to a draft or archived idea would be hidden
+	if survivorStatus == StatusDraft || survivorStatus == StatusArchived {
+		apperror.Abort(c, apperror.Validation("merge_into_unlisted", "Ideas can only be merged into a published idea", apperror.FieldError{
+			Field:   "into",
+			Message: "must be a published idea",
+		}))
+		return
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	var survivor Idea
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		if err := mergeLikes(sessCtx, db, duplicateID, survivorID); err != nil {
+			return nil, err
+		}
+		if err := mergeBookmarks(sessCtx, db, duplicateID, survivorID); err != nil {
+			return nil, err
+		}
+		if _, err := db.Collection("comments").UpdateMany(sessCtx,
+			bson.M{"idea_id": duplicateID},
+			bson.M{"$set": bson.M{"idea_id": survivorID}},
+		); err != nil {
+			return nil, err
+		}
+
+		likes, err := db.Collection("likes").CountDocuments(sessCtx, bson.M{"idea_id": survivorID})
+		if err != nil {
+			return nil, err
+		}
+		comments, err := db.Collection("comments").CountDocuments(sessCtx, Live(bson.M{"idea_id": survivorID}))
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if _, err := ideasColl.UpdateOne(sessCtx,
+			bson.M{"_id": duplicateID},
+			bson.M{"$set": bson.M{
+				"deleted_at":     now,
+				"deleted_by":     c.GetString("user_id"),
+				"merged_into":    survivorID,
+				"likes_count":    0,
+				"comments_count": 0,
+			}},
+		); err != nil {
+			return nil, err
+		}
+
+		return nil, ideasColl.FindOneAndUpdate(sessCtx,
+			bson.M{"_id": survivorID},
+			bson.M{"$set": bson.M{
+				"likes_count":    likes,
+				"comments_count": comments,
+				"updated_at":     now,
+			}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&survivor)
+	})
+	if err != nil {
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) {
+			err = apperror.Internal("merge_ideas_failed", "Failed to merge ideas", err)
+		}
+		apperror.Abort(c, err)
+		return
+	}
+
+	// The duplicate left the listings, so it no longer counts as a fork
+	var duplicate Idea
+	err = ideasColl.FindOne(ctx,
+		bson.M{"_id": duplicateID},
+		options.FindOne().SetProjection(bson.M{"forked_from": 1}),
+	).Decode(&duplicate)
+	if err == nil {
+		err = recountForkSource(ctx, db, &duplicate)
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_forks_count_failed", "Failed to update fork count", err))
+		return
+	}
+	// The survivor's likes changed, which ranks it in autocomplete
+	h.indexes.Refresh(ctx, db, duplicateID)
+	h.indexes.Refresh(ctx, db, survivorID)
+
+	NormalizeStatus(&survivor)
+	c.JSON(http.StatusOK, gin.H{"data": survivor})
+}
+
+// mergeLikes moves likes from duplicateID to survivorID, dropping the likes
+// of users who already like the survivor
+func mergeLikes(ctx context.Context, db *mongo.Database, duplicateID, survivorID bson.ObjectID) error {
+	likes := db.Collection("likes")
+
+	var likers []string
+	if err := likes.Distinct(ctx, "user_id", bson.M{"idea_id": survivorID}).Decode(&likers); err != nil {
+		return err
+	}
+	if len(likers) > 0 {
+		if _, err := likes.DeleteMany(ctx, bson.M{
+			"idea_id": duplicateID,
+			"user_id": bson.M{"$in": likers},
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err := likes.UpdateMany(ctx,
+		bson.M{"idea_id": duplicateID},
+		bson.M{"$set": bson.M{"idea_id": survivorID}},
+	)
+	return err
+}
+
+// mergeBookmarks moves bookmarks from duplicateID to survivorID. Users who
+// bookmarked both keep their survivor bookmark, which also gets filed into
+// the duplicate bookmark's collections.
+func mergeBookmarks(ctx context.Context, db *mongo.Database, duplicateID, survivorID bson.ObjectID) error {
+	bookmarks := db.Collection("bookmarks")
+
+	var both []string
+	if err := bookmarks.Distinct(ctx, "user_id", bson.M{"idea_id": survivorID}).Decode(&both); err != nil {
+		return err
+	}
+	if len(both) > 0 {
+		cursor, err := bookmarks.Find(ctx, bson.M{"idea_id": duplicateID, "user_id": bson.M{"$in": both}})
+		if err != nil {
+			return err
+		}
+		var overlapping []Bookmark
+		if err := cursor.All(ctx, &overlapping); err != nil {
+			return err
+		}
+		for _, b := range overlapping {
+			if len(b.CollectionIDs) > 0 {
+				if _, err := bookmarks.UpdateOne(ctx,
+					bson.M{"idea_id": survivorID, "user_id": b.UserID},
+					bson.M{"$addToSet": bson.M{"collection_ids": bson.M{"$each": b.CollectionIDs}}},
+				); err != nil {
+					return err
+				}
+			}
+			if _, err := bookmarks.DeleteOne(ctx, bson.M{"_id": b.ID}); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := bookmarks.UpdateMany(ctx,
+		bson.M{"idea_id": duplicateID},
+		bson.M{"$set": bson.M{"idea_id": survivorID}},
+	)
+	return err
+}
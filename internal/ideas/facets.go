@@ -0,0 +1,146 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// defaultFacetSize is how many tags are counted when facet_size isn't given
+	defaultFacetSize = 10
+	// maxFacetSize caps facet_size
+	maxFacetSize = 50
+)
+
+// FacetCount is how many ideas matching the current filter have a value
+type FacetCount struct {
+	Value string `bson:"_id" json:"value"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+// Facets are the tag and difficulty counts of the ideas matching a filter
+type Facets struct {
+	Tags       []FacetCount `bson:"tags" json:"tags"`
+	Difficulty []FacetCount `bson:"difficulty" json:"difficulty"`
+}
+
+// parseFacets reads the facets and facet_size query parameters. size is 0
+// when facets weren't requested.
+func parseFacets(c *gin.Context) (int, *apperror.Error) {
+	raw := c.Query("facets")
+	if raw == "" {
+		return 0, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return 0, apperror.Validation("invalid_facets", "Invalid facets flag", apperror.FieldError{
+			Field:   "facets",
+			Message: "must be true or false",
+		})
+	}
+	if !enabled {
+		return 0, nil
+	}
+
+	size := defaultFacetSize
+	if raw := c.Query("facet_size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxFacetSize {
+			return 0, apperror.Validation("invalid_facet_size", "Invalid facet size", apperror.FieldError{
+				Field:   "facet_size",
+				Message: "must be between 1 and " + strconv.Itoa(maxFacetSize),
+			})
+		}
+		size = parsed
+	}
+	return size, nil
+}
+
+// findWithFacets fetches a page of ideas matching filter together with the
+// total and the top facetSize tag counts and the difficulty counts, in a
+// single $facet aggregation. relevance sorts by text search score instead
+// of sort.
+func findWithFacets(ctx context.Context, collection *mongo.Collection, filter bson.M, sort bson.D, relevance bool, skip, limit int64, facetSize int) ([]ListedIdea, int64, *Facets, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+	}
+	if _, ok := filter["$text"]; ok {
+		// Copy the score into a field, since the $facet sub-pipelines
+		// can't read text search metadata
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.D{
+			{Key: "text_score", Value: bson.D{{Key: "$meta", Value: "textScore"}}},
+		}}})
+		if relevance {
+			sort = bson.D{{Key: "text_score", Value: -1}, {Key: "created_at", Value: -1}}
+		}
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$facet", Value: bson.D{
+			{Key: "results", Value: bson.A{
+				bson.D{{Key: "$sort", Value: sort}},
+				bson.D{{Key: "$skip", Value: skip}},
+				bson.D{{Key: "$limit", Value: limit}},
+			}},
+			{Key: "total", Value: bson.A{
+				bson.D{{Key: "$count", Value: "count"}},
+			}},
+			{Key: "tags", Value: bson.A{
+				bson.D{{Key: "$unwind", Value: "$tags"}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$tags"},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+				bson.D{{Key: "$limit", Value: facetSize}},
+			}},
+			{Key: "difficulty", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "difficulty", Value: bson.D{{Key: "$nin", Value: bson.A{nil, ""}}}}}}},
+				bson.D{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: "$difficulty"},
+					{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+				}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+			}},
+		}}},
+	)
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Results []ListedIdea `bson:"results"`
+		Total   []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+		Tags       []FacetCount `bson:"tags"`
+		Difficulty []FacetCount `bson:"difficulty"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, 0, nil, err
+	}
+
+	facets := &Facets{Tags: []FacetCount{}, Difficulty: []FacetCount{}}
+	if len(results) == 0 {
+		return nil, 0, facets, nil
+	}
+	r := results[0]
+	var total int64
+	if len(r.Total) > 0 {
+		total = r.Total[0].Count
+	}
+	if r.Tags != nil {
+		facets.Tags = r.Tags
+	}
+	if r.Difficulty != nil {
+		facets.Difficulty = r.Difficulty
+	}
+	return r.Results, total, facets, nil
+}
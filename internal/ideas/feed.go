@@ -0,0 +1,437 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// feedSignals caps how many recent likes, bookmarks and dismissals shape
+	// a user's profile. Older ones still keep their ideas out of the feed.
+	feedSignals = 200
+	// feedCandidates caps how many unseen ideas are ranked per request
+	feedCandidates = 500
+	// feedTopTags is how many of a user's favourite tags pull in older ideas
+	feedTopTags = 10
+	// feedWindow is how far back ideas are considered regardless of tags
+	feedWindow = 60 * 24 * time.Hour
+
+	// Weights of a user's signals when building their profile
+	likeWeight     = 1.0
+	bookmarkWeight = 2.0
+	dismissWeight  = -1.5
+
+	// affinityWeight is how much the profile counts against the hot score
+	affinityWeight = 0.65
+	// hotGravity controls how fast the hot score decays with age
+	hotGravity = 1.5
+	// Each idea already ranked above with a shared tag or the same author
+	// scales a candidate's score by these factors
+	tagRepeatDecay    = 0.85
+	authorRepeatDecay = 0.7
+)
+
+// Dismissal records that a user isn't interested in an idea
+type Dismissal struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string        `bson:"user_id" json:"user_id"`
+	IdeaID    bson.ObjectID `bson:"idea_id" json:"idea_id"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+}
+
+// FeedIdea is an idea ranked for the caller, with the tags from their
+// profile that it matched
+type FeedIdea struct {
+	Idea        `bson:",inline"`
+	Score       float64  `json:"score"`
+	MatchedTags []string `json:"matched_tags"`
+}
+
+// feedProfile is how much a user likes each tag and difficulty, from their
+// likes and bookmarks, less their dismissals
+type feedProfile struct {
+	tags         map[string]float64
+	difficulties map[string]float64
+}
+
+// feedSignalCollections hold the likes, bookmarks and dismissals whose ideas
+// are left out of a user's feed
+var feedSignalCollections = []string{"likes", "bookmarks", "dismissals"}
+
+// hotScore ranks ideas by engagement, decaying with age
+func hotScore(idea *Idea, now time.Time) float64 {
+	engagement := float64(idea.LikesCount) + 2*float64(idea.CommentsCount) + 3*float64(idea.BuilderCounts.Shipped)
+	published := idea.CreatedAt
+	if idea.PublishedAt != nil {
+		published = *idea.PublishedAt
+	}
+	ageHours := math.Max(now.Sub(published).Hours(), 0)
+	return math.Log10(math.Max(engagement, 1)+1) / math.Pow(ageHours+2, hotGravity)
+}
+
+// signalIdeaIDs reads the idea IDs of a user's recent likes, bookmarks or
+// dismissals
+func signalIdeaIDs(ctx context.Context, coll *mongo.Collection, userID string) ([]bson.ObjectID, error) {
+	cursor, err := coll.Find(ctx,
+		bson.M{"user_id": userID},
+		options.Find().
+			SetProjection(bson.M{"idea_id": 1}).
+			SetSort(bson.D{{Key: "created_at", Value: -1}}).
+			SetLimit(feedSignals),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var docs []struct {
+		IdeaID bson.ObjectID `bson:"idea_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]bson.ObjectID, len(docs))
+	for i, d := range docs {
+		ids[i] = d.IdeaID
+	}
+	return ids, nil
+}
+
+// loadFeedProfile weighs the tags and difficulties of the ideas a user
+// liked, bookmarked and dismissed
+func loadFeedProfile(ctx context.Context, db *mongo.Database, userID string) (*feedProfile, error) {
+	profile := &feedProfile{
+		tags:         map[string]float64{},
+		difficulties: map[string]float64{},
+	}
+
+	weights := map[bson.ObjectID]float64{}
+	for _, signal := range []struct {
+		collection string
+		weight     float64
+	}{
+		{"likes", likeWeight},
+		{"bookmarks", bookmarkWeight},
+		{"dismissals", dismissWeight},
+	} {
+		ids, err := signalIdeaIDs(ctx, db.Collection(signal.collection), userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			weights[id] += signal.weight
+		}
+	}
+	if len(weights) == 0 {
+		return profile, nil
+	}
+
+	ids := make([]bson.ObjectID, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+
+	cursor, err := db.Collection("ideas").Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"tags": 1, "difficulty": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var signalled []Idea
+	if err := cursor.All(ctx, &signalled); err != nil {
+		return nil, err
+	}
+	for _, idea := range signalled {
+		w := weights[idea.ID]
+		for _, tag := range idea.Tags {
+			profile.tags[tag] += w
+		}
+		if idea.Difficulty != "" {
+			profile.difficulties[idea.Difficulty] += w
+		}
+	}
+	return profile, nil
+}
+
+// topTags returns the tags the user likes most, strongest first
+func (p *feedProfile) topTags(n int) []string {
+	tags := make([]string, 0, len(p.tags))
+	for tag, w := range p.tags {
+		if w > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if p.tags[tags[i]] != p.tags[tags[j]] {
+			return p.tags[tags[i]] > p.tags[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+	if len(tags) > n {
+		tags = tags[:n]
+	}
+	return tags
+}
+
+// affinity scores how well idea fits the profile, along with the liked tags
+// it matched. Disliked tags and difficulties make the score negative.
+func (p *feedProfile) affinity(idea *Idea) (float64, []string) {
+	var score float64
+	matched := []string{}
+	for _, tag := range idea.Tags {
+		w := p.tags[tag]
+		score += w
+		if w > 0 {
+			matched = append(matched, tag)
+		}
+	}
+	score += p.difficulties[idea.Difficulty] / 2
+	return score, matched
+}
+
+// rankFeed blends affinity and hot score, both scaled to the best candidate,
+// then reorders greedily so one tag or author doesn't fill the feed
+func rankFeed(candidates []Idea, profile *feedProfile, now time.Time) []FeedIdea {
+	hot := make([]float64, len(candidates))
+	affinity := make([]float64, len(candidates))
+	matched := make([][]string, len(candidates))
+	var maxHot, maxAffinity float64
+	for i := range candidates {
+		hot[i] = hotScore(&candidates[i], now)
+		affinity[i], matched[i] = profile.affinity(&candidates[i])
+		maxHot = math.Max(maxHot, hot[i])
+		maxAffinity = math.Max(maxAffinity, math.Abs(affinity[i]))
+	}
+
+	base := make([]float64, len(candidates))
+	for i := range candidates {
+		var h, a float64
+		if maxHot > 0 {
+			h = hot[i] / maxHot
+		}
+		if maxAffinity > 0 {
+			a = affinity[i] / maxAffinity
+		}
+		if maxAffinity == 0 {
+			// No usable signals yet, so rank by hot score alone
+			base[i] = h
+		} else {
+			base[i] = math.Max(affinityWeight*a+(1-affinityWeight)*h, 0)
+		}
+	}
+
+	ranked := make([]FeedIdea, 0, len(candidates))
+	picked := make([]bool, len(candidates))
+	tagCounts := map[string]int{}
+	authorCounts := map[string]int{}
+	for len(ranked) < len(candidates) {
+		best, bestScore := -1, -1.0
+		for i := range candidates {
+			if picked[i] {
+				continue
+			}
+			repeats := 0
+			for _, tag := range candidates[i].Tags {
+				repeats = max(repeats, tagCounts[tag])
+			}
+			score := base[i] *
+				math.Pow(tagRepeatDecay, float64(repeats)) *
+				math.Pow(authorRepeatDecay, float64(authorCounts[candidates[i].AuthorID]))
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		idea := candidates[best]
+		for _, tag := range idea.Tags {
+			tagCounts[tag]++
+		}
+		authorCounts[idea.AuthorID]++
+		NormalizeStatus(&idea)
+		ranked = append(ranked, FeedIdea{Idea: idea, Score: bestScore, MatchedTags: matched[best]})
+	}
+	return ranked
+}
+
+// GetFeed ranks unseen ideas for the caller by how well their tags and
+// difficulty match what the caller liked and bookmarked, blended with the
+// hot score. Ideas the caller wrote, liked, bookmarked or dismissed are left out.
+func (h *Handler) GetFeed(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	profile, err := loadFeedProfile(ctx, db, userID)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_feed_profile_failed", "Failed to load feed profile", err))
+		return
+	}
+
+	now := time.Now()
+	recent := bson.A{
+		bson.M{"created_at": bson.M{"$gte": now.Add(-feedWindow)}},
+		bson.M{"published_at": bson.M{"$gte": now.Add(-feedWindow)}},
+	}
+	if tags := profile.topTags(feedTopTags); len(tags) > 0 {
+		recent = append(recent, bson.M{"tags": bson.M{"$in": tags}})
+	}
+	filter := Live(bson.M{
+		"status":    listedStatuses(),
+		"author_id": bson.M{"$ne": userID},
+		"$or":       recent,
+	})
+
+	// Anti-join every like, bookmark and dismissal of the caller, however
+	// old, rather than only the recent ones the profile is built from.
+	// Lookups stop once enough candidates pass.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.D{{Key: "likes_count", Value: -1}, {Key: "created_at", Value: -1}}}},
+	}
+	unseen := bson.M{}
+	for _, coll := range feedSignalCollections {
+		field := "seen_" + coll
+		pipeline = append(pipeline, bson.D{{Key: "$lookup", Value: bson.M{
+			"from": coll,
+			"let":  bson.M{"idea_id": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"user_id": userID,
+					"$expr":   bson.M{"$eq": bson.A{"$idea_id", "$$idea_id"}},
+				}}},
+				{{Key: "$limit", Value: 1}},
+				{{Key: "$project", Value: bson.M{"_id": 1}}},
+			},
+			"as": field,
+		}}})
+		unseen[field] = bson.M{"$size": 0}
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: unseen}},
+		bson.D{{Key: "$limit", Value: feedCandidates}},
+	)
+
+	cursor, err := db.Collection("ideas").Aggregate(ctx, pipeline)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+	var candidates []Idea
+	if err := cursor.All(ctx, &candidates); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+		return
+	}
+
+	ranked := rankFeed(candidates, profile, now)
+
+	page, pageSize := pagination.Parse(c)
+	start := min((page-1)*pageSize, len(ranked))
+	end := min(start+pageSize, len(ranked))
+	refs := make([]*Idea, 0, end-start)
+	for i := start; i < end; i++ {
+		refs = append(refs, &ranked[i].Idea)
+	}
+	EmbedAuthors(ctx, db, refs...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       ranked[start:end],
+		"pagination": pagination.Meta(page, pageSize, int64(len(ranked))),
+	})
+}
+
+// DismissIdea marks an idea as not interesting to the caller. It leaves the
+// feed and its tags and difficulty count against similar ideas.
+func (h *Handler) DismissIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	exists, err := db.Collection("ideas").CountDocuments(ctx, Live(bson.M{"_id": ideaID, "status": bson.M{"$ne": StatusDraft}}))
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("check_idea_failed", "Failed to check idea", err))
+		return
+	}
+	if exists == 0 {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
+		return
+	}
+
+	// Upsert against the unique user_id+idea_id index so dismissing twice is harmless
+	_, err = db.Collection("dismissals").UpdateOne(ctx,
+		bson.M{"user_id": c.GetString("user_id"), "idea_id": ideaID},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("dismiss_idea_failed", "Failed to dismiss idea", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Idea dismissed successfully"})
+}
+
+// UndismissIdea lets a dismissed idea back into the caller's feed
+func (h *Handler) UndismissIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	result, err := db.Collection("dismissals").DeleteOne(ctx, bson.M{
+		"user_id": c.GetString("user_id"),
+		"idea_id": ideaID,
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("undismiss_idea_failed", "Failed to undo dismissal", err))
+		return
+	}
+	if result.DeletedCount == 0 {
+		apperror.Abort(c, apperror.NotFound("dismissal_not_found", "Dismissal not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Idea dismissal undone successfully"})
+}
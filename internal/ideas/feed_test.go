@@ -0,0 +1,149 @@
+package ideas
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestHotScore(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	published := func(age time.Duration, likes int) *Idea {
+		at := now.Add(-age)
+		return &Idea{LikesCount: likes, CreatedAt: at, PublishedAt: &at}
+	}
+
+	if hotScore(published(time.Hour, 50), now) <= hotScore(published(time.Hour, 5), now) {
+		t.Error("more likes should score higher at the same age")
+	}
+	if hotScore(published(48*time.Hour, 10), now) >= hotScore(published(time.Hour, 10), now) {
+		t.Error("older ideas should score lower with the same engagement")
+	}
+
+	// Comments and shipped builds count more than likes
+	liked := &Idea{LikesCount: 6, CreatedAt: now}
+	shipped := &Idea{BuilderCounts: BuilderCounts{Shipped: 2}, CreatedAt: now}
+	if got, want := hotScore(shipped, now), hotScore(liked, now); got != want {
+		t.Errorf("2 shipped builds scored %v, want the same as 6 likes (%v)", got, want)
+	}
+
+	// Age runs from publication, not creation
+	old := now.Add(-30 * 24 * time.Hour)
+	draftedLongAgo := published(time.Hour, 10)
+	draftedLongAgo.CreatedAt = old
+	if got, want := hotScore(draftedLongAgo, now), hotScore(published(time.Hour, 10), now); got != want {
+		t.Errorf("hotScore() = %v for an old draft published now, want %v", got, want)
+	}
+
+	// Clock skew doesn't make future ideas score higher than brand new ones
+	if hotScore(published(-time.Hour, 10), now) != hotScore(published(0, 10), now) {
+		t.Error("ideas published in the future should score as if published now")
+	}
+}
+
+func TestFeedProfileTopTags(t *testing.T) {
+	p := &feedProfile{tags: map[string]float64{
+		"go":     3,
+		"rust":   3,
+		"react":  1,
+		"jquery": -1.5,
+		"cobol":  0,
+	}}
+
+	if got, want := p.topTags(10), []string{"go", "rust", "react"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("topTags(10) = %v, want %v", got, want)
+	}
+	if got, want := p.topTags(1), []string{"go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("topTags(1) = %v, want %v", got, want)
+	}
+}
+
+func TestFeedProfileAffinity(t *testing.T) {
+	p := &feedProfile{
+		tags:         map[string]float64{"go": 2, "cli": 1, "jquery": -1.5},
+		difficulties: map[string]float64{"beginner": 1},
+	}
+
+	score, matched := p.affinity(&Idea{Tags: []string{"go", "cli", "web"}, Difficulty: "beginner"})
+	if score != 3.5 {
+		t.Errorf("affinity score = %v, want 3.5", score)
+	}
+	if want := []string{"go", "cli"}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("matched tags = %v, want %v", matched, want)
+	}
+
+	score, matched = p.affinity(&Idea{Tags: []string{"jquery"}})
+	if score >= 0 || len(matched) != 0 {
+		t.Errorf("affinity of a disliked tag = %v %v, want a negative score and no matches", score, matched)
+	}
+}
+
+func TestRankFeed(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	idea := func(title, author string, likes int, tags ...string) Idea {
+		return Idea{ID: bson.NewObjectID(), Title: title, AuthorID: author, Tags: tags, LikesCount: likes, CreatedAt: now.Add(-time.Hour)}
+	}
+	titles := func(ranked []FeedIdea) []string {
+		got := make([]string, len(ranked))
+		for i, r := range ranked {
+			got[i] = r.Title
+		}
+		return got
+	}
+	empty := &feedProfile{tags: map[string]float64{}, difficulties: map[string]float64{}}
+
+	t.Run("without signals ranks by hot score", func(t *testing.T) {
+		ranked := rankFeed([]Idea{
+			idea("quiet", "a", 1, "go"),
+			idea("popular", "b", 100, "rust"),
+		}, empty, now)
+		if got, want := titles(ranked), []string{"popular", "quiet"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("rankFeed() = %v, want %v", got, want)
+		}
+		if ranked[0].Score != 1 {
+			t.Errorf("top score = %v, want 1 once scaled to the best candidate", ranked[0].Score)
+		}
+	})
+
+	t.Run("affinity outweighs a little engagement", func(t *testing.T) {
+		profile := &feedProfile{tags: map[string]float64{"go": 3}, difficulties: map[string]float64{}}
+		ranked := rankFeed([]Idea{
+			idea("rust", "a", 20, "rust"),
+			idea("go", "b", 10, "go"),
+		}, profile, now)
+		if got, want := titles(ranked), []string{"go", "rust"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("rankFeed() = %v, want %v", got, want)
+		}
+		if want := []string{"go"}; !reflect.DeepEqual(ranked[0].MatchedTags, want) {
+			t.Errorf("matched tags = %v, want %v", ranked[0].MatchedTags, want)
+		}
+	})
+
+	t.Run("disliked ideas score zero", func(t *testing.T) {
+		profile := &feedProfile{tags: map[string]float64{"jquery": -2, "go": 1}, difficulties: map[string]float64{}}
+		ranked := rankFeed([]Idea{idea("jquery", "a", 50, "jquery"), idea("go", "b", 0, "go")}, profile, now)
+		if last := ranked[len(ranked)-1]; last.Title != "jquery" || last.Score != 0 {
+			t.Errorf("last = %s with %v, want jquery with 0", last.Title, last.Score)
+		}
+	})
+
+	t.Run("one author doesn't fill the top", func(t *testing.T) {
+		ranked := rankFeed([]Idea{
+			idea("a1", "prolific", 30, "go"),
+			idea("a2", "prolific", 29, "rust"),
+			idea("b1", "other", 26, "react"),
+		}, empty, now)
+		if got, want := titles(ranked), []string{"a1", "b1", "a2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("rankFeed() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("statuses are normalized", func(t *testing.T) {
+		ranked := rankFeed([]Idea{idea("legacy", "a", 1)}, empty, now)
+		if ranked[0].Status != StatusPublished {
+			t.Errorf("status = %q, want %q", ranked[0].Status, StatusPublished)
+		}
+	})
+}
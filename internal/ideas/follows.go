@@ -0,0 +1,446 @@
+package ideas
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Follow types
+const (
+	FollowTag    = "tag"
+	FollowAuthor = "author"
+)
+
+// Follow is a user following a tag, by its slug, or an author, by user ID
+type Follow struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    string        `bson:"user_id" json:"user_id"`
+	Type      string        `bson:"type" json:"type"`
+	Target    string        `bson:"target" json:"target"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+}
+
+// FollowCounts are how many users follow a user as an author and how many
+// tags and authors the user follows
+type FollowCounts struct {
+	FollowersCount        int64 `json:"followers_count"`
+	FollowingTagsCount    int64 `json:"following_tags_count"`
+	FollowingAuthorsCount int64 `json:"following_authors_count"`
+}
+
+// followCounts counts the follows of and by userID
+func followCounts(ctx context.Context, db *mongo.Database, userID string) (*FollowCounts, error) {
+	follows := db.Collection("follows")
+	var counts FollowCounts
+	var err error
+	if counts.FollowersCount, err = follows.CountDocuments(ctx, bson.M{"type": FollowAuthor, "target": userID}); err != nil {
+		return nil, err
+	}
+	if counts.FollowingTagsCount, err = follows.CountDocuments(ctx, bson.M{"user_id": userID, "type": FollowTag}); err != nil {
+		return nil, err
+	}
+	if counts.FollowingAuthorsCount, err = follows.CountDocuments(ctx, bson.M{"user_id": userID, "type": FollowAuthor}); err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// follow adds a follow of target by userID. Following twice is harmless.
+func follow(ctx context.Context, db *mongo.Database, userID, followType, target string) error {
+	// Upsert against the unique user_id+type+target index
+	_, err := db.Collection("follows").UpdateOne(ctx,
+		bson.M{"user_id": userID, "type": followType, "target": target},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// unfollow removes the follow of target by userID, if any
+func unfollow(ctx context.Context, db *mongo.Database, userID, followType, target string) error {
+	_, err := db.Collection("follows").DeleteOne(ctx,
+		bson.M{"user_id": userID, "type": followType, "target": target},
+	)
+	return err
+}
+
+// findFollowedTag loads the tag a follow request is for, resolving synonyms
+func (h *Handler) findFollowedTag(ctx context.Context, c *gin.Context, db *mongo.Database) (*Tag, error) {
+	slug := c.Param("slug")
+	if resolved := h.taxonomy.resolve(ctx, []string{slug}); len(resolved) > 0 {
+		slug = resolved[0]
+	}
+	return findTag(ctx, db, slug)
+}
+
+// FollowTag adds a tag to the caller's following feed
+func (h *Handler) FollowTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	tag, err := h.findFollowedTag(ctx, c, db)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := follow(ctx, db, c.GetString("user_id"), FollowTag, tag.Slug); err != nil {
+		apperror.Abort(c, apperror.Internal("follow_tag_failed", "Failed to follow tag", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag followed successfully"})
+}
+
+// UnfollowTag removes a tag from the caller's following feed
+func (h *Handler) UnfollowTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	tag, err := h.findFollowedTag(ctx, c, db)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := unfollow(ctx, db, c.GetString("user_id"), FollowTag, tag.Slug); err != nil {
+		apperror.Abort(c, apperror.Internal("unfollow_tag_failed", "Failed to unfollow tag", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag unfollowed successfully"})
+}
+
+// FollowAuthor adds an author to the caller's following feed. Authors are
+// users with at least one idea, and users can't follow themselves.
+func (h *Handler) FollowAuthor(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	authorID := c.Param("id")
+	if authorID == c.GetString("user_id") {
+		apperror.Abort(c, apperror.Validation("follow_self", "You can't follow yourself", apperror.FieldError{
+			Field:   "id",
+			Message: "must be another user",
+		}))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	n, err := db.Collection("ideas").CountDocuments(ctx,
+		Live(bson.M{"author_id": authorID, "status": listedStatuses()}),
+		options.Count().SetLimit(1),
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("check_author_failed", "Failed to check author", err))
+		return
+	}
+	if n == 0 {
+		apperror.Abort(c, apperror.NotFound("author_not_found", "Author not found"))
+		return
+	}
+
+	if err := follow(ctx, db, c.GetString("user_id"), FollowAuthor, authorID); err != nil {
+		apperror.Abort(c, apperror.Internal("follow_author_failed", "Failed to follow author", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Author followed successfully"})
+}
+
+// UnfollowAuthor removes an author from the caller's following feed
+func (h *Handler) UnfollowAuthor(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if err := unfollow(ctx, db, c.GetString("user_id"), FollowAuthor, c.Param("id")); err != nil {
+		apperror.Abort(c, apperror.Internal("unfollow_author_failed", "Failed to unfollow author", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Author unfollowed successfully"})
+}
+
+// ListFollows lists the tags and authors the caller follows, most recently
+// followed first, optionally only one type
+func (h *Handler) ListFollows(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	filter := bson.M{"user_id": c.GetString("user_id")}
+	switch followType := c.Query("type"); followType {
+	case "":
+	case FollowTag, FollowAuthor:
+		filter["type"] = followType
+	default:
+		apperror.Abort(c, apperror.Validation("invalid_follow_type", "Invalid follow type", apperror.FieldError{
+			Field:   "type",
+			Message: "must be tag or author",
+		}))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	page, pageSize := pagination.Parse(c)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := db.Collection("follows").Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_follows_failed", "Failed to fetch follows", err))
+		return
+	}
+	follows := []Follow{}
+	if err := cursor.All(ctx, &follows); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_follows_failed", "Failed to decode follows", err))
+		return
+	}
+
+	total, err := db.Collection("follows").CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_follows_failed", "Failed to count follows", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       follows,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// GetFollowCounts returns how many users follow a user and how many tags and
+// authors the user follows
+func (h *Handler) GetFollowCounts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	counts, err := followCounts(ctx, h.client.Database(cfg.MongoDBConfig.Database), c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_follows_failed", "Failed to count follows", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": counts})
+}
+
+// feedCursor is the position after the last idea of a following feed page
+type feedCursor struct {
+	publishedAt time.Time
+	id          bson.ObjectID
+}
+
+// encode makes the cursor an opaque URL-safe string
+func (fc feedCursor) encode() string {
+	raw := strconv.FormatInt(fc.publishedAt.UnixMilli(), 10) + "." + fc.id.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseFeedCursor(s string) (*feedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	millis, hex, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return nil, errors.New("malformed cursor")
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := bson.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil, err
+	}
+	return &feedCursor{publishedAt: time.UnixMilli(ms), id: id}, nil
+}
+
+// GetFollowingFeed lists ideas by the authors and with the tags the caller
+// follows, most recently published first. Pages are fetched with the
+// next_cursor of the previous page, so ideas added meanwhile don't shift them.
+// Ideas are placed by when they were published, so a draft published later
+// shows up at the top rather than behind pages already read.
+func (h *Handler) GetFollowingFeed(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var after *feedCursor
+	if raw := c.Query("cursor"); raw != "" {
+		if after, err = parseFeedCursor(raw); err != nil {
+			apperror.Abort(c, apperror.Validation("invalid_cursor", "Invalid cursor", apperror.FieldError{
+				Field:   "cursor",
+				Message: "must be the next_cursor of a previous page",
+			}))
+			return
+		}
+	}
+	_, pageSize := pagination.Parse(c)
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	cursor, err := db.Collection("follows").Find(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_follows_failed", "Failed to fetch follows", err))
+		return
+	}
+	var follows []Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_follows_failed", "Failed to decode follows", err))
+		return
+	}
+
+	var authors, tags []string
+	for _, f := range follows {
+		if f.Type == FollowAuthor {
+			authors = append(authors, f.Target)
+		} else {
+			tags = append(tags, f.Target)
+		}
+	}
+	ideas := []Idea{}
+	if len(authors) == 0 && len(tags) == 0 {
+		c.JSON(http.StatusOK, gin.H{"data": ideas, "next_cursor": nil})
+		return
+	}
+
+	followed := bson.A{}
+	if len(authors) > 0 {
+		followed = append(followed, bson.M{"author_id": bson.M{"$in": authors}})
+	}
+	if len(tags) > 0 {
+		followed = append(followed, bson.M{"tags": bson.M{"$in": h.taxonomy.expand(ctx, tags)}})
+	}
+	conditions := bson.A{bson.M{"$or": followed}}
+	if after != nil {
+		conditions = append(conditions, bson.M{"$or": bson.A{
+			bson.M{"published_at": bson.M{"$lt": after.publishedAt}},
+			bson.M{"published_at": after.publishedAt, "_id": bson.M{"$lt": after.id}},
+		}})
+	}
+	// Listed ideas stored without published_at get it from the
+	// backfill_published_at migration. Asking for it lets the sparse
+	// published_at+_id index serve the sort.
+	filter := Live(bson.M{
+		"status":       listedStatuses(),
+		"author_id":    bson.M{"$ne": userID},
+		"published_at": bson.M{"$exists": true},
+		"$and":         conditions,
+	})
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "published_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(pageSize) + 1)
+	cursor, err = db.Collection("ideas").Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+	if err := cursor.All(ctx, &ideas); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+		return
+	}
+
+	// The extra idea only tells whether there is a next page
+	var next *string
+	if len(ideas) > pageSize {
+		ideas = ideas[:pageSize]
+		last := ideas[len(ideas)-1]
+		encoded := feedCursor{publishedAt: *last.PublishedAt, id: last.ID}.encode()
+		next = &encoded
+	}
+	refs := make([]*Idea, len(ideas))
+	for i := range ideas {
+		NormalizeStatus(&ideas[i])
+		refs[i] = &ideas[i]
+	}
+	EmbedAuthors(ctx, db, refs...)
+
+	c.JSON(http.StatusOK, gin.H{"data": ideas, "next_cursor": next})
+}
+
+// mergeTagFollows moves the follows of merged tags to slug, dropping the
+// ones of users who already follow it
+func mergeTagFollows(ctx context.Context, db *mongo.Database, merged []string, slug string) error {
+	follows := db.Collection("follows")
+
+	var followers []string
+	if err := follows.Distinct(ctx, "user_id", bson.M{"type": FollowTag, "target": slug}).Decode(&followers); err != nil {
+		return err
+	}
+	if len(followers) > 0 {
+		if _, err := follows.DeleteMany(ctx, bson.M{
+			"type":    FollowTag,
+			"target":  bson.M{"$in": merged},
+			"user_id": bson.M{"$in": followers},
+		}); err != nil {
+			return err
+		}
+	}
+
+	_, err := follows.UpdateMany(ctx,
+		bson.M{"type": FollowTag, "target": bson.M{"$in": merged}},
+		bson.M{"$set": bson.M{"target": slug}},
+	)
+	return err
+}
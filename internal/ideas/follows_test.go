@@ -0,0 +1,46 @@
+package ideas
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestFeedCursorRoundTrip(t *testing.T) {
+	want := feedCursor{
+		publishedAt: time.Date(2025, 5, 4, 10, 30, 15, 250_000_000, time.UTC),
+		id:          bson.NewObjectID(),
+	}
+
+	got, err := parseFeedCursor(want.encode())
+	if err != nil {
+		t.Fatalf("parseFeedCursor(encode()) error: %v", err)
+	}
+	if !got.publishedAt.Equal(want.publishedAt) || got.id != want.id {
+		t.Errorf("parseFeedCursor(encode()) = %+v, want %+v", *got, want)
+	}
+}
+
+func TestParseFeedCursorErrors(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := map[string]string{
+		"not base64":        "%%%",
+		"padded base64":     base64.URLEncoding.EncodeToString([]byte("1.abc")),
+		"no separator":      encode("1714816215000"),
+		"invalid millis":    encode("yesterday.6630d1c2e4b0a1b2c3d4e5f6"),
+		"invalid object id": encode("1714816215000.zzz"),
+		"empty":             "",
+	}
+	for name, cursor := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseFeedCursor(cursor); err == nil {
+				t.Errorf("parseFeedCursor(%q): want an error", cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// LineageEntry is one idea in a fork chain. Ideas the caller can't see, such
+// as drafts or deleted ideas, keep their place but only expose their ID.
+type LineageEntry struct {
+	ID        bson.ObjectID `bson:"_id" json:"id"`
+	Title     string        `bson:"title" json:"title,omitempty"`
+	AuthorID  string        `bson:"author_id" json:"author_id,omitempty"`
+	Available bool          `bson:"-" json:"available"`
+}
+
+// recountForks sets the forks_count of each source idea to its number of
+// listed forks. Drafts, archived and trashed forks aren't counted, matching
+// what GetForks lists.
+func recountForks(ctx context.Context, db *mongo.Database, sourceIDs ...bson.ObjectID) error {
+	ideas := db.Collection("ideas")
+	for _, sourceID := range sourceIDs {
+		n, err := ideas.CountDocuments(ctx, Live(bson.M{"forked_from": sourceID, "status": listedStatuses()}))
+		if err != nil {
+			return err
+		}
+		if _, err := ideas.UpdateOne(ctx,
+			bson.M{"_id": sourceID},
+			bson.M{"$set": bson.M{"forks_count": n}},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recountForkSource recounts the forks of the idea that idea was forked from
+func recountForkSource(ctx context.Context, db *mongo.Database, idea *Idea) error {
+	if idea.ForkedFrom == nil {
+		return nil
+	}
+	return recountForks(ctx, db, *idea.ForkedFrom)
+}
+
+// ForkIdea copies an idea, with its details, into a new draft owned by the
+// caller. The source's forks_count only changes once the fork is published.
+func (h *Handler) ForkIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	source, err := findVisibleIdea(ctx, c, db, ideaID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	var details *IdeaDetails
+	var found IdeaDetails
+	err = db.Collection("idea_details").FindOne(ctx, bson.M{"idea_id": ideaID}).Decode(&found)
+	if err == nil {
+		details = &found
+	} else if err != mongo.ErrNoDocuments {
+		apperror.Abort(c, apperror.Internal("fetch_details_failed", "Failed to fetch idea details", err))
+		return
+	}
+
+	now := time.Now()
+	fork := Idea{
+		ID:          bson.NewObjectID(),
+		Title:       source.Title,
+		Description: source.Description,
+		Tags:        source.Tags,
+		Difficulty:  source.Difficulty,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		AuthorID:    c.GetString("user_id"),
+		Status:      StatusDraft,
+		ForkedFrom:  &source.ID,
+		Ancestors:   append(append([]bson.ObjectID{}, source.Ancestors...), source.ID),
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		if _, err := db.Collection("ideas").InsertOne(sessCtx, fork); err != nil {
+			return nil, err
+		}
+
+		if details != nil {
+			details.UpdatedAt = now
+			_, err := db.Collection("idea_details").UpdateOne(sessCtx,
+				bson.M{"idea_id": fork.ID},
+				bson.M{"$setOnInsert": details},
+				options.UpdateOne().SetUpsert(true),
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fork_idea_failed", "Failed to fork idea", err))
+		return
+	}
+
+	fork.Details = details
+	c.JSON(http.StatusCreated, gin.H{"data": fork})
+}
+
+// GetForks lists the published forks of an idea, newest first
+func (h *Handler) GetForks(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if _, err := findVisibleIdea(ctx, c, db, ideaID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	collection := db.Collection("ideas")
+	filter := Live(bson.M{"forked_from": ideaID, "status": listedStatuses()})
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var forks []Idea
+	if err := cursor.All(ctx, &forks); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+		return
+	}
+	refs := make([]*Idea, len(forks))
+	for i := range forks {
+		NormalizeStatus(&forks[i])
+		refs[i] = &forks[i]
+	}
+	EmbedAuthors(ctx, db, refs...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       forks,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// GetLineage returns the chain of ideas an idea was forked from, starting
+// with the original and ending with the idea itself
+func (h *Handler) GetLineage(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	idea, err := findVisibleIdea(ctx, c, db, ideaID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	lineage, err := loadLineage(ctx, c, db, idea)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_lineage_failed", "Failed to fetch lineage", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lineage})
+}
+
+// loadLineage resolves idea's ancestors in order, hiding the ones the caller can't see
+func loadLineage(ctx context.Context, c *gin.Context, db *mongo.Database, idea *Idea) ([]LineageEntry, error) {
+	lineage := make([]LineageEntry, 0, len(idea.Ancestors)+1)
+	if len(idea.Ancestors) > 0 {
+		cursor, err := db.Collection("ideas").Find(ctx,
+			bson.M{"_id": bson.M{"$in": idea.Ancestors}},
+			options.Find().SetProjection(bson.M{"title": 1, "author_id": 1, "status": 1, "deleted_at": 1}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		var ancestors []Idea
+		if err := cursor.All(ctx, &ancestors); err != nil {
+			return nil, err
+		}
+
+		userID := c.GetString("user_id")
+		byID := make(map[bson.ObjectID]Idea, len(ancestors))
+		for _, a := range ancestors {
+			byID[a.ID] = a
+		}
+		for _, id := range idea.Ancestors {
+			a, ok := byID[id]
+			visible := ok && a.DeletedAt == nil && (a.Status != StatusDraft || a.AuthorID == userID || isAdmin(c))
+			if !visible {
+				lineage = append(lineage, LineageEntry{ID: id})
+				continue
+			}
+			lineage = append(lineage, LineageEntry{ID: id, Title: a.Title, AuthorID: a.AuthorID, Available: true})
+		}
+	}
+
+	return append(lineage, LineageEntry{
+		ID:        idea.ID,
+		Title:     idea.Title,
+		AuthorID:  idea.AuthorID,
+		Available: true,
+	}), nil
+}
@@ -2,12 +2,17 @@ package ideas
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/metrics"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"ikurotime/backlog-go-backend/internal/tracing"
 	"log"
-	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,71 +21,180 @@ import (
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// Difficulty levels an idea can be tagged with
+const (
+	DifficultyBeginner     = "beginner"
+	DifficultyIntermediate = "intermediate"
+	DifficultyAdvanced     = "advanced"
+)
+
+// Statuses an idea moves through. Ideas stored before statuses existed have
+// no status field and are treated as published.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// RoleAdmin is the Clerk public metadata role allowed to act on any idea
+const RoleAdmin = "admin"
+
+// A failed index setup is retried in the background, waiting twice as long
+// after each failure, from indexRetryMin up to indexRetryMax
+const (
+	indexRetryMin = 5 * time.Second
+	indexRetryMax = 5 * time.Minute
+)
+
 // Idea represents a project idea in the database
 type Idea struct {
-	ID            bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	Title         string        `bson:"title" json:"title"`
-	Description   string        `bson:"description" json:"description"`
-	Tags          []string      `bson:"tags" json:"tags"`
-	Difficulty    string        `bson:"difficulty" json:"difficulty"`
-	CreatedAt     time.Time     `bson:"created_at" json:"created_at"`
-	UpdatedAt     time.Time     `bson:"updated_at" json:"updated_at"`
-	AuthorID      string        `bson:"author_id" json:"author_id"`
-	LikesCount    int           `bson:"likes_count" json:"likes_count"`
-	CommentsCount int           `bson:"comments_count" json:"comments_count"`
-	Details       interface{}   `bson:"details,omitempty" json:"details,omitempty"`
+	ID            bson.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Title         string          `bson:"title" json:"title"`
+	Description   string          `bson:"description" json:"description"`
+	Tags          []string        `bson:"tags" json:"tags"`
+	Difficulty    string          `bson:"difficulty" json:"difficulty"`
+	CreatedAt     time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time       `bson:"updated_at" json:"updated_at"`
+	AuthorID      string          `bson:"author_id" json:"author_id"`
+	Author        *AuthorSummary  `bson:"-" json:"author,omitempty"`
+	LikesCount    int             `bson:"likes_count" json:"likes_count"`
+	CommentsCount int             `bson:"comments_count" json:"comments_count"`
+	RevisionCount int             `bson:"revision_count" json:"revision_count"`
+	BuilderCounts BuilderCounts   `bson:"builder_counts" json:"builder_counts"`
+	ForksCount    int             `bson:"forks_count" json:"forks_count"`
+	ForkedFrom    *bson.ObjectID  `bson:"forked_from,omitempty" json:"forked_from,omitempty"`
+	Ancestors     []bson.ObjectID `bson:"ancestors,omitempty" json:"-"`
+	Status        string          `bson:"status,omitempty" json:"status"`
+	PublishAt     *time.Time      `bson:"publish_at,omitempty" json:"publish_at,omitempty"`
+	PublishedAt   *time.Time      `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	DeletedAt     *time.Time      `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy     string          `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	MergedInto    *bson.ObjectID  `bson:"merged_into,omitempty" json:"merged_into,omitempty"`
+	Details       *IdeaDetails    `bson:"details,omitempty" json:"details,omitempty"`
 }
 
 // Like represents a user's like on an idea
 type Like struct {
-	ID        string    `bson:"_id,omitempty"`
-	UserID    string    `bson:"user_id"`
-	IdeaID    string    `bson:"idea_id"`
-	CreatedAt time.Time `bson:"created_at"`
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	UserID    string        `bson:"user_id"`
+	IdeaID    bson.ObjectID `bson:"idea_id"`
+	CreatedAt time.Time     `bson:"created_at"`
 }
 
 // Comment represents a user's comment on an idea
 type Comment struct {
-	ID        string    `bson:"_id,omitempty" json:"id"`
-	IdeaID    string    `bson:"idea_id" json:"idea_id"`
-	UserID    string    `bson:"user_id" json:"user_id"`
-	Content   string    `bson:"content" json:"content"`
-	CreatedAt time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	IdeaID    bson.ObjectID `bson:"idea_id" json:"idea_id"`
+	UserID    string        `bson:"user_id" json:"user_id"`
+	Content   string        `bson:"content" json:"content"`
+	CreatedAt time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time     `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time    `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy string        `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
-// Bookmark represents a user's bookmark on an idea
+// Bookmark represents a user's bookmark on an idea, optionally filed into
+// one or more collections with a private note
 type Bookmark struct {
-	ID        bson.ObjectID `bson:"_id,omitempty"`
-	UserID    string        `bson:"user_id"`
-	IdeaID    bson.ObjectID `bson:"idea_id"`
-	CreatedAt time.Time     `bson:"created_at"`
+	ID            bson.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID        string          `bson:"user_id" json:"user_id"`
+	IdeaID        bson.ObjectID   `bson:"idea_id" json:"idea_id"`
+	CollectionIDs []bson.ObjectID `bson:"collection_ids,omitempty" json:"collection_ids"`
+	Note          string          `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt     time.Time       `bson:"created_at" json:"created_at"`
+}
+
+// BookmarkInfo is the caller's bookmark metadata attached to a bookmarked idea
+type BookmarkInfo struct {
+	Note          string          `bson:"note,omitempty" json:"note,omitempty"`
+	CollectionIDs []bson.ObjectID `bson:"collection_ids,omitempty" json:"collection_ids"`
+	CreatedAt     time.Time       `bson:"created_at" json:"created_at"`
+}
+
+// BookmarkedIdea is an idea as returned from the caller's bookmark listings
+type BookmarkedIdea struct {
+	Idea     `bson:",inline"`
+	Bookmark BookmarkInfo `bson:"bookmark" json:"bookmark"`
+}
+
+type createIdeaRequest struct {
+	Title       string     `json:"title" binding:"required,min=3,max=200"`
+	Description string     `json:"description" binding:"required,max=10000"`
+	Tags        []string   `json:"tags" binding:"max=20,dive,required,max=50"`
+	Difficulty  string     `json:"difficulty" binding:"required,oneof=beginner intermediate advanced"`
+	Status      string     `json:"status" binding:"omitempty,oneof=draft published"`
+	PublishAt   *time.Time `json:"publish_at"`
+}
+
+type updateIdeaRequest struct {
+	Title       *string   `json:"title" binding:"omitempty,min=3,max=200"`
+	Description *string   `json:"description" binding:"omitempty,max=10000"`
+	Tags        *[]string `json:"tags" binding:"omitempty,max=20,dive,required,max=50"`
+	Difficulty  *string   `json:"difficulty" binding:"omitempty,oneof=beginner intermediate advanced"`
+}
+
+// isAdmin reports whether the authenticated caller has the admin role
+func isAdmin(c *gin.Context) bool {
+	return c.GetString("user_role") == RoleAdmin
+}
+
+// Live restricts filter to ideas or comments that haven't been moved to the trash
+func Live(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+// listedStatuses matches ideas that appear in public listings and search,
+// including ideas stored before statuses existed
+func listedStatuses() bson.M {
+	return bson.M{"$nin": bson.A{StatusDraft, StatusArchived}}
+}
+
+// NormalizeStatus fills in the status of ideas stored before statuses existed
+func NormalizeStatus(idea *Idea) {
+	if idea.Status == "" {
+		idea.Status = StatusPublished
+	}
+}
+
+func errInvalidIdeaID() *apperror.Error {
+	return apperror.Validation("invalid_idea_id", "Invalid idea ID", apperror.FieldError{
+		Field:   "id",
+		Message: "must be a 24 character hex ObjectID",
+	})
 }
 
 // Handler handles idea-related HTTP requests
 type Handler struct {
-	client *mongo.Client
+	client   *mongo.Client
+	indexes  *ideaIndexes
+	taxonomy *taxonomy
+	tagStats *tagStatsCache
+
+	profileSyncs *profileSyncs
+
+	indexMu  sync.RWMutex
+	indexErr error
 }
 
 // NewHandler creates a new ideas handler
 func NewHandler(client *mongo.Client) *Handler {
 	handler := &Handler{
-		client: client,
-	}
+		client:   client,
+		indexes:  newIdeaIndexes(client),
+		taxonomy: newTaxonomy(client),
+		tagStats: newTagStatsCache(),
 
-	// Setup indexes on initialization
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		log.Printf("Failed to load config: %v", err)
-		return handler
+		profileSyncs: &profileSyncs{synced: map[string]time.Time{}},
 	}
 
+	// Setup indexes on initialization
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	db := client.Database(cfg.MongoDBConfig.Database)
-	if err := handler.setupIndexes(ctx, db); err != nil {
+	if err := handler.ensureIndexes(ctx); err != nil {
 		log.Printf("Failed to setup indexes: %v", err)
+		go handler.retryIndexes(context.Background())
 	} else {
 		log.Print("MongoDB indexes created successfully")
 	}
@@ -88,6 +202,81 @@ func NewHandler(client *mongo.Client) *Handler {
 	return handler
 }
 
+// retryIndexes retries a failed index setup with backoff until it succeeds
+// or ctx is cancelled
+func (h *Handler) retryIndexes(ctx context.Context) {
+	delay := indexRetryMin
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := h.ensureIndexes(attemptCtx)
+		cancel()
+		if err == nil {
+			log.Print("MongoDB indexes created successfully")
+			return
+		}
+		delay = min(delay*2, indexRetryMax)
+		log.Printf("Failed to setup indexes, retrying in %s: %v", delay, err)
+	}
+}
+
+// ensureIndexes runs setupIndexes and remembers the outcome for readiness checks
+func (h *Handler) ensureIndexes(ctx context.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		h.setIndexErr(err)
+		return err
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	// Duplicates left by the old check-then-insert bookmarking would make
+	// the unique bookmark index fail to build. Once it exists there can be
+	// none, so the full scan is skipped.
+	unique, err := hasUniqueBookmarkIndex(ctx, db)
+	if err != nil {
+		h.setIndexErr(err)
+		return err
+	}
+	if !unique {
+		removed, err := RemoveDuplicateBookmarks(ctx, db)
+		if err != nil {
+			h.setIndexErr(err)
+			return err
+		}
+		if removed > 0 {
+			log.Printf("Removed %d duplicate bookmarks", removed)
+		}
+	}
+
+	err = h.setupIndexes(ctx, db)
+	h.setIndexErr(err)
+	return err
+}
+
+func (h *Handler) setIndexErr(err error) {
+	h.indexMu.Lock()
+	defer h.indexMu.Unlock()
+	h.indexErr = err
+}
+
+// CheckIndexes reports whether the indexes were created. A failed setup is
+// retried in the background rather than by the readiness probe.
+func (h *Handler) CheckIndexes(ctx context.Context) error {
+	h.indexMu.RLock()
+	err := h.indexErr
+	h.indexMu.RUnlock()
+	if err != nil {
+		return errors.New("indexes not created: " + err.Error())
+	}
+	return nil
+}
+
 // setupIndexes creates necessary indexes for optimal query performance
 func (h *Handler) setupIndexes(ctx context.Context, db *mongo.Database) error {
 	// Ideas collection indexes
@@ -113,6 +302,49 @@ func (h *Handler) setupIndexes(ctx context.Context, db *mongo.Database) error {
 				{Key: "created_at", Value: -1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "author_id", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "updated_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "publish_at", Value: 1},
+			},
+		},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "forked_from", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "builder_counts.shipped", Value: -1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "published_at", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "index_changed_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 		{
 			Keys: bson.D{
 				{Key: "title", Value: "text"},
@@ -146,6 +378,237 @@ func (h *Handler) setupIndexes(ctx context.Context, db *mongo.Database) error {
 		return err
 	}
 
+	// Bookmarks collection indexes
+	bookmarksColl := db.Collection("bookmarks")
+	_, err = bookmarksColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "idea_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "collection_ids", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Bookmark collections indexes
+	collectionsColl := db.Collection("bookmark_collections")
+	_, err = collectionsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "position", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Idea revisions collection indexes
+	revisionsColl := db.Collection("idea_revisions")
+	_, err = revisionsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "idea_id", Value: 1},
+			{Key: "number", Value: -1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Idea builds collection indexes
+	buildsColl := db.Collection("idea_builds")
+	_, err = buildsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "idea_id", Value: 1},
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "idea_id", Value: 1},
+				{Key: "status", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "updated_at", Value: -1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Dismissals collection indexes
+	dismissalsColl := db.Collection("dismissals")
+	_, err = dismissalsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "idea_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Saved searches collection indexes
+	savedSearchesColl := db.Collection("saved_searches")
+	_, err = savedSearchesColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Notifications collection indexes
+	notificationsColl := db.Collection("notifications")
+	_, err = notificationsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "saved_search_id", Value: 1},
+				{Key: "idea_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "saved_search_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "read_at", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tags collection indexes. A synonym belongs to one tag only; tags
+	// without synonyms are left out so their empty arrays don't collide.
+	tagsColl := db.Collection("tags")
+	_, err = tagsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "synonyms", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"synonyms": bson.M{"$type": "string"},
+			}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Follows collection indexes
+	followsColl := db.Collection("follows")
+	_, err = followsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "type", Value: 1},
+				{Key: "target", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "type", Value: 1},
+				{Key: "target", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Users collection indexes
+	usersColl := db.Collection("users")
+	_, err = usersColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "synced_at", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Idea details collection indexes
+	detailsColl := db.Collection("idea_details")
+	_, err = detailsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "idea_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
 	// Comments collection indexes
 	commentsColl := db.Collection("comments")
 	_, err = commentsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
@@ -161,6 +624,10 @@ func (h *Handler) setupIndexes(ctx context.Context, db *mongo.Database) error {
 				{Key: "created_at", Value: -1},
 			},
 		},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	})
 	return err
 }
@@ -172,7 +639,7 @@ func (h *Handler) GetAll(c *gin.Context) {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 
@@ -180,22 +647,43 @@ func (h *Handler) GetAll(c *gin.Context) {
 	collection := db.Collection("ideas")
 
 	// Build filter based on query parameters
-	filter := bson.M{}
-	if tags := c.QueryArray("tags"); len(tags) > 0 {
-		filter["tags"] = bson.M{"$in": tags}
-	}
-	if difficulty := c.Query("difficulty"); difficulty != "" {
-		filter["difficulty"] = difficulty
+	filter, query, filterErr := listFilter(ctx, h.taxonomy, c.QueryArray("tags"), c.Query("difficulty"), c.Query("search"))
+	if filterErr != nil {
+		apperror.Abort(c, filterErr)
+		return
 	}
-	if search := c.Query("search"); search != "" {
-		// Use text index for more efficient searching
-		filter["$text"] = bson.M{"$search": search}
+	_, textSearch := filter["$text"]
+	if built := c.Query("built"); built != "" {
+		isBuilt, err := strconv.ParseBool(built)
+		if err != nil {
+			apperror.Abort(c, apperror.Validation("invalid_built", "Invalid built filter", apperror.FieldError{
+				Field:   "built",
+				Message: "must be true or false",
+			}))
+			return
+		}
+		// Ideas stored before builds existed have no builder_counts
+		if isBuilt {
+			filter["builder_counts.shipped"] = bson.M{"$gt": 0}
+		} else {
+			filter["builder_counts.shipped"] = bson.M{"$not": bson.M{"$gt": 0}}
+		}
 	}
 
 	// Build sort options
 	sort := bson.D{{Key: "created_at", Value: -1}}
+	relevance := false
 	if sortBy := c.Query("sort"); sortBy != "" {
 		switch sortBy {
+		case "relevance":
+			// Without search words there's no relevance, so keep newest first
+			if textSearch {
+				relevance = true
+				sort = bson.D{
+					{Key: "text_score", Value: bson.M{"$meta": "textScore"}},
+					{Key: "created_at", Value: -1},
+				}
+			}
 		case "trending":
 			sort = bson.D{
 				{Key: "likes_count", Value: -1},
@@ -207,27 +695,46 @@ func (h *Handler) GetAll(c *gin.Context) {
 		}
 	}
 
+	facetSize, facetErr := parseFacets(c)
+	if facetErr != nil {
+		apperror.Abort(c, facetErr)
+		return
+	}
+
 	// Execute query with pagination
-	page := 1
-	pageSize := 20 // Default page size
+	page, pageSize := pagination.Parse(c)
+
+	skip := int64((page - 1) * pageSize)
 
-	if p := c.Query("page"); p != "" {
-		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
-			page = parsedPage
+	// Facets come back with the page and total from a single aggregation
+	if facetSize > 0 {
+		ideas, total, facets, err := findWithFacets(ctx, collection, filter, sort, relevance, skip, int64(pageSize), facetSize)
+		if err != nil {
+			apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+			return
 		}
-	}
-	if size := c.Query("size"); size != "" {
-		if parsedSize, err := strconv.Atoi(size); err == nil && parsedSize > 0 && parsedSize <= 100 {
-			pageSize = parsedSize
+		refs := make([]*Idea, len(ideas))
+		for i := range ideas {
+			NormalizeStatus(&ideas[i].Idea)
+			refs[i] = &ideas[i].Idea
+		}
+		EmbedAuthors(ctx, db, refs...)
+		if query != nil {
+			highlight(ideas, query)
 		}
-	}
 
-	skip := int64((page - 1) * pageSize)
+		c.JSON(http.StatusOK, gin.H{
+			"data":       ideas,
+			"facets":     facets,
+			"pagination": pagination.Meta(page, pageSize, total),
+		})
+		return
+	}
 
 	// Get total count for pagination
 	total, err := collection.CountDocuments(ctx, filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count ideas"})
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
 		return
 	}
 
@@ -235,57 +742,59 @@ func (h *Handler) GetAll(c *gin.Context) {
 		SetSort(sort).
 		SetSkip(skip).
 		SetLimit(int64(pageSize))
+	if textSearch {
+		opts.SetProjection(bson.M{"text_score": bson.M{"$meta": "textScore"}})
+	}
 
 	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ideas"})
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
 		return
 	}
 	defer cursor.Close(ctx)
 
-	var ideas []Idea
+	var ideas []ListedIdea
 	if err := cursor.All(ctx, &ideas); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode ideas", "message": err.Error()})
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
 		return
 	}
-
-	// Calculate pagination metadata
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-	hasNext := page < totalPages
-	hasPrev := page > 1
+	refs := make([]*Idea, len(ideas))
+	for i := range ideas {
+		NormalizeStatus(&ideas[i].Idea)
+		refs[i] = &ideas[i].Idea
+	}
+	EmbedAuthors(ctx, db, refs...)
+	if query != nil {
+		highlight(ideas, query)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": ideas,
-		"pagination": gin.H{
-			"current_page": page,
-			"page_size":    pageSize,
-			"total_items":  total,
-			"total_pages":  totalPages,
-			"has_next":     hasNext,
-			"has_prev":     hasPrev,
-		},
+		"data":       ideas,
+		"pagination": pagination.Meta(page, pageSize, total),
 	})
 }
 
+// GetOne retrieves a single idea with its details. Drafts are only visible
+// to their author and admins.
 func (h *Handler) GetOne(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c, 10*time.Second)
 	defer cancel()
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 	db := h.client.Database(cfg.MongoDBConfig.Database)
 	collection := db.Collection("ideas")
 	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid idea ID"})
+		apperror.Abort(c, errInvalidIdeaID())
 		return
 	}
 
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.D{{Key: "_id", Value: ideaID}}}},
+		{{Key: "$match", Value: Live(bson.M{"_id": ideaID})}},
 		{{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: "idea_details"},
 			{Key: "let", Value: bson.D{{Key: "ideaId", Value: "$_id"}}},
@@ -306,30 +815,106 @@ func (h *Handler) GetOne(c *gin.Context) {
 
 	cursor, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch idea", "message": err.Error()})
+		apperror.Abort(c, apperror.Internal("fetch_idea_failed", "Failed to fetch idea", err))
 		return
 	}
 	defer cursor.Close(ctx)
 
 	var results []Idea
 	if err = cursor.All(ctx, &results); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode idea", "message": err.Error()})
+		apperror.Abort(c, apperror.Internal("decode_idea_failed", "Failed to decode idea", err))
 		return
 	}
 
 	if len(results) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Idea not found"})
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
 		return
 	}
 
 	idea := results[0]
-	fmt.Printf("Fetched idea with details: %+v\n", idea)
+	if idea.Status == StatusDraft && idea.AuthorID != c.GetString("user_id") && !isAdmin(c) {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
+		return
+	}
+	NormalizeStatus(&idea)
+	EmbedAuthors(ctx, h.client.Database(cfg.MongoDBConfig.Database), &idea)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": idea,
 	})
 }
 
+// UpdateIdea edits an idea's title, description, tags or difficulty and
+// records the result as a new revision
+func (h *Handler) UpdateIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var req updateIdeaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	idea, err := findAuthoredIdea(ctx, db, ideaID, userID, isAdmin(c))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if req.Title != nil {
+		set["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		set["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Tags != nil {
+		tags, err := h.taxonomy.normalize(ctx, db, *req.Tags)
+		if err != nil {
+			apperror.Abort(c, apperror.Internal("normalize_tags_failed", "Failed to normalize tags", err))
+			return
+		}
+		set["tags"] = tags
+	}
+	if req.Difficulty != nil {
+		set["difficulty"] = *req.Difficulty
+	}
+
+	_, err = recordEdit(ctx, db, idea, userID, nil, func() error {
+		_, err := db.Collection("ideas").UpdateOne(ctx, bson.M{"_id": ideaID}, bson.M{"$set": set})
+		return err
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_idea_failed", "Failed to update idea", err))
+		return
+	}
+
+	var updated Idea
+	if err := db.Collection("ideas").FindOne(ctx, bson.M{"_id": ideaID}).Decode(&updated); err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_idea_failed", "Failed to fetch idea", err))
+		return
+	}
+	h.indexes.apply(&updated)
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
 // LikeIdea handles liking an idea with transaction support
 func (h *Handler) LikeIdea(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c, 10*time.Second)
@@ -337,25 +922,36 @@ func (h *Handler) LikeIdea(c *gin.Context) {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 
 	// Get user ID from context (set by auth middleware)
 	userID := c.GetString("user_id")
-	ideaID := c.Param("id")
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
 
 	db := h.client.Database(cfg.MongoDBConfig.Database)
 
 	// Start transaction
 	session, err := db.Client().StartSession()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
 		return
 	}
 	defer session.EndSession(ctx)
 
-	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+	txCtx, span := tracing.Start(ctx, "mongodb.transaction.like")
+	liked, err := session.WithTransaction(txCtx, func(sessCtx context.Context) (interface{}, error) {
+		// Trashed ideas and drafts can't be liked, so their counts stay frozen
+		ideasColl := db.Collection("ideas")
+		if err := checkLikeable(sessCtx, ideasColl, ideaID); err != nil {
+			return nil, err
+		}
+
 		// Check if like already exists
 		likesColl := db.Collection("likes")
 		exists, err := likesColl.CountDocuments(sessCtx, bson.M{
@@ -366,7 +962,7 @@ func (h *Handler) LikeIdea(c *gin.Context) {
 			return nil, err
 		}
 		if exists > 0 {
-			return nil, nil // Like already exists
+			return false, nil // Like already exists
 		}
 
 		// Insert like
@@ -380,23 +976,43 @@ func (h *Handler) LikeIdea(c *gin.Context) {
 		}
 
 		// Increment likes_count in ideas collection
-		ideasColl := db.Collection("ideas")
 		_, err = ideasColl.UpdateOne(
 			sessCtx,
 			bson.M{"_id": ideaID},
 			bson.M{"$inc": bson.M{"likes_count": 1}},
 		)
-		return nil, err
+		return err == nil, err
 	})
+	tracing.End(span, err)
 
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		apperror.Abort(c, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to like idea"})
+		apperror.Abort(c, apperror.Internal("like_idea_failed", "Failed to like idea", err))
 		return
 	}
+	if ok, _ := liked.(bool); ok {
+		metrics.Likes.WithLabelValues("like").Inc()
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Idea liked successfully"})
 }
 
+// checkLikeable fails with a 404 unless the idea is live and not a draft
+func checkLikeable(ctx context.Context, ideas *mongo.Collection, ideaID bson.ObjectID) error {
+	exists, err := ideas.CountDocuments(ctx, Live(bson.M{"_id": ideaID, "status": bson.M{"$ne": StatusDraft}}))
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return apperror.NotFound("idea_not_found", "Idea not found")
+	}
+	return nil
+}
+
 // UnlikeIdea handles unliking an idea with transaction support
 func (h *Handler) UnlikeIdea(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c, 10*time.Second)
@@ -404,23 +1020,33 @@ func (h *Handler) UnlikeIdea(c *gin.Context) {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 
 	userID := c.GetString("user_id")
-	ideaID := c.Param("id")
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
 
 	db := h.client.Database(cfg.MongoDBConfig.Database)
 
 	session, err := db.Client().StartSession()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
 		return
 	}
 	defer session.EndSession(ctx)
 
-	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+	txCtx, span := tracing.Start(ctx, "mongodb.transaction.unlike")
+	unliked, err := session.WithTransaction(txCtx, func(sessCtx context.Context) (interface{}, error) {
+		ideasColl := db.Collection("ideas")
+		if err := checkLikeable(sessCtx, ideasColl, ideaID); err != nil {
+			return nil, err
+		}
+
 		// Delete like
 		likesColl := db.Collection("likes")
 		result, err := likesColl.DeleteOne(sessCtx, bson.M{
@@ -431,23 +1057,31 @@ func (h *Handler) UnlikeIdea(c *gin.Context) {
 			return nil, err
 		}
 		if result.DeletedCount == 0 {
-			return nil, nil // Like didn't exist
+			return false, nil // Like didn't exist
 		}
 
 		// Decrement likes_count in ideas collection
-		ideasColl := db.Collection("ideas")
 		_, err = ideasColl.UpdateOne(
 			sessCtx,
 			bson.M{"_id": ideaID},
 			bson.M{"$inc": bson.M{"likes_count": -1}},
 		)
-		return nil, err
+		return err == nil, err
 	})
+	tracing.End(span, err)
 
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		apperror.Abort(c, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlike idea"})
+		apperror.Abort(c, apperror.Internal("unlike_idea_failed", "Failed to unlike idea", err))
 		return
 	}
+	if ok, _ := unliked.(bool); ok {
+		metrics.Likes.WithLabelValues("unlike").Inc()
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Idea unliked successfully"})
 }
@@ -459,42 +1093,45 @@ func (h *Handler) BookmarkIdea(c *gin.Context) {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 
 	userID := c.GetString("user_id")
 	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid idea ID"})
+		apperror.Abort(c, errInvalidIdeaID())
 		return
 	}
 
 	db := h.client.Database(cfg.MongoDBConfig.Database)
-	bookmarksColl := db.Collection("bookmarks")
 
-	exists, err := bookmarksColl.CountDocuments(ctx, bson.M{
-		"user_id": userID,
-		"idea_id": ideaID,
-	})
+	exists, err := db.Collection("ideas").CountDocuments(ctx, Live(bson.M{"_id": ideaID, "status": bson.M{"$ne": StatusDraft}}))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bookmark"})
+		apperror.Abort(c, apperror.Internal("check_idea_failed", "Failed to check idea", err))
 		return
 	}
-	if exists > 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "Already bookmarked"})
+	if exists == 0 {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
 		return
 	}
 
-	_, err = bookmarksColl.InsertOne(ctx, Bookmark{
-		UserID:    userID,
-		IdeaID:    ideaID,
-		CreatedAt: time.Now(),
-	})
+	// Upsert against the unique user_id+idea_id index so concurrent requests
+	// can't create duplicate bookmarks
+	result, err := db.Collection("bookmarks").UpdateOne(ctx,
+		bson.M{"user_id": userID, "idea_id": ideaID},
+		bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+		options.UpdateOne().SetUpsert(true),
+	)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to bookmark idea"})
+		apperror.Abort(c, apperror.Internal("bookmark_idea_failed", "Failed to bookmark idea", err))
+		return
+	}
+	if result.UpsertedCount == 0 {
+		apperror.Abort(c, apperror.Conflict("bookmark_exists", "Idea is already bookmarked"))
 		return
 	}
+	metrics.Bookmarks.WithLabelValues("bookmark").Inc()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Idea bookmarked successfully"})
 }
@@ -506,14 +1143,14 @@ func (h *Handler) UnbookmarkIdea(c *gin.Context) {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 
 	userID := c.GetString("user_id")
 	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid idea ID"})
+		apperror.Abort(c, errInvalidIdeaID())
 		return
 	}
 
@@ -525,13 +1162,14 @@ func (h *Handler) UnbookmarkIdea(c *gin.Context) {
 		"idea_id": ideaID,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unbookmark idea"})
+		apperror.Abort(c, apperror.Internal("unbookmark_idea_failed", "Failed to unbookmark idea", err))
 		return
 	}
 	if result.DeletedCount == 0 {
-		c.JSON(http.StatusOK, gin.H{"message": "Bookmark not found"})
+		apperror.Abort(c, apperror.NotFound("bookmark_not_found", "Bookmark not found"))
 		return
 	}
+	metrics.Bookmarks.WithLabelValues("unbookmark").Inc()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Idea unbookmarked successfully"})
 }
@@ -543,34 +1181,26 @@ func (h *Handler) GetBookmarkedIdeas(c *gin.Context) {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load config"})
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
 		return
 	}
 
 	userID := c.GetString("user_id")
 	db := h.client.Database(cfg.MongoDBConfig.Database)
 
-	page := 1
-	pageSize := 20
-
-	if p := c.Query("page"); p != "" {
-		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
-			page = parsedPage
-		}
-	}
-	if size := c.Query("size"); size != "" {
-		if parsedSize, err := strconv.Atoi(size); err == nil && parsedSize > 0 && parsedSize <= 100 {
-			pageSize = parsedSize
-		}
-	}
+	h.listBookmarkedIdeas(ctx, c, db, bson.M{"user_id": userID})
+}
 
+// listBookmarkedIdeas writes a page of the ideas referenced by the bookmarks
+// matching filter, each annotated with the caller's bookmark metadata
+func (h *Handler) listBookmarkedIdeas(ctx context.Context, c *gin.Context, db *mongo.Database, filter bson.M) {
+	page, pageSize := pagination.Parse(c)
 	skip := int64((page - 1) * pageSize)
 
-	pipeline := []bson.D{
-		{{Key: "$match", Value: bson.M{"user_id": userID}}},
-		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
-		{{Key: "$skip", Value: skip}},
-		{{Key: "$limit", Value: pageSize}},
+	// Bookmarked ideas that were moved back to draft or to the trash are
+	// hidden, so the idea lookup happens before paging and counting
+	base := []bson.D{
+		{{Key: "$match", Value: filter}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "ideas",
 			"localField":   "idea_id",
@@ -578,41 +1208,62 @@ func (h *Handler) GetBookmarkedIdeas(c *gin.Context) {
 			"as":           "idea",
 		}}},
 		{{Key: "$unwind", Value: "$idea"}},
-		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$idea"}}},
+		{{Key: "$match", Value: bson.M{
+			"idea.status":     bson.M{"$ne": StatusDraft},
+			"idea.deleted_at": bson.M{"$exists": false},
+		}}},
 	}
 
+	pipeline := append(append([]bson.D{}, base...),
+		bson.D{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		bson.D{{Key: "$skip", Value: skip}},
+		bson.D{{Key: "$limit", Value: pageSize}},
+		bson.D{{Key: "$replaceRoot", Value: bson.M{"newRoot": bson.M{
+			"$mergeObjects": bson.A{"$idea", bson.M{"bookmark": bson.M{
+				"note":           "$note",
+				"collection_ids": "$collection_ids",
+				"created_at":     "$created_at",
+			}}},
+		}}}},
+	)
+
 	cursor, err := db.Collection("bookmarks").Aggregate(ctx, pipeline)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookmarked ideas"})
+		apperror.Abort(c, apperror.Internal("fetch_bookmarked_ideas_failed", "Failed to fetch bookmarked ideas", err))
 		return
 	}
 	defer cursor.Close(ctx)
 
-	var ideas []Idea
+	var ideas []BookmarkedIdea
 	if err := cursor.All(ctx, &ideas); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode ideas"})
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
 		return
 	}
+	for i := range ideas {
+		NormalizeStatus(&ideas[i].Idea)
+	}
 
-	total, err := db.Collection("bookmarks").CountDocuments(ctx, bson.M{"user_id": userID})
+	countCursor, err := db.Collection("bookmarks").Aggregate(ctx, append(base, bson.D{{Key: "$count", Value: "total"}}))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count bookmarks"})
+		apperror.Abort(c, apperror.Internal("count_bookmarks_failed", "Failed to count bookmarks", err))
 		return
 	}
+	defer countCursor.Close(ctx)
 
-	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
-	hasNext := page < totalPages
-	hasPrev := page > 1
+	var counts []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := countCursor.All(ctx, &counts); err != nil {
+		apperror.Abort(c, apperror.Internal("count_bookmarks_failed", "Failed to count bookmarks", err))
+		return
+	}
+	var total int64
+	if len(counts) > 0 {
+		total = counts[0].Total
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": ideas,
-		"pagination": gin.H{
-			"current_page": page,
-			"page_size":    pageSize,
-			"total_items":  total,
-			"total_pages":  totalPages,
-			"has_next":     hasNext,
-			"has_prev":     hasPrev,
-		},
+		"data":       ideas,
+		"pagination": pagination.Meta(page, pageSize, total),
 	})
 }
@@ -0,0 +1,202 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/similarity"
+	"ikurotime/backlog-go-backend/internal/suggest"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// syncSkew re-reads changes this far before the last sync, so writes that
+	// committed late aren't missed
+	syncSkew = 5 * time.Second
+	// reconcileInterval is how often every listed idea is re-read. Liking an
+	// idea doesn't change its updated_at, and ideas purged by another
+	// instance leave no trace for the incremental sync to see.
+	reconcileInterval = 10 * time.Minute
+)
+
+// ideaIndexes keeps the in-memory indexes of listed ideas, for similar ideas
+// and autocomplete, in sync with MongoDB
+type ideaIndexes struct {
+	client  *mongo.Client
+	similar *similarity.Index
+	suggest *suggest.Index
+
+	syncMu        sync.Mutex
+	loaded        bool
+	lastSync      time.Time
+	lastReconcile time.Time
+}
+
+func newIdeaIndexes(client *mongo.Client) *ideaIndexes {
+	return &ideaIndexes{
+		client:  client,
+		similar: similarity.NewIndex(similarTopN),
+		suggest: suggest.NewIndex(),
+	}
+}
+
+// apply indexes idea if it is listed and drops it otherwise
+func (s *ideaIndexes) apply(idea *Idea) {
+	id := idea.ID.Hex()
+	listed := idea.DeletedAt == nil && idea.Status != StatusDraft && idea.Status != StatusArchived
+	if listed {
+		s.similar.Upsert(id, similarityDocument(idea))
+		s.suggest.Upsert(id, idea.Title, idea.Tags, idea.LikesCount)
+	} else {
+		s.similar.Remove(id)
+		s.suggest.Remove(id)
+	}
+}
+
+// Sync loads every listed idea on the first call and afterwards only ideas
+// changed, published or deleted since the previous call
+func (s *ideaIndexes) Sync(ctx context.Context) error {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	collection := s.client.Database(cfg.MongoDBConfig.Database).Collection("ideas")
+
+	started := time.Now()
+	filter := Live(bson.M{"status": listedStatuses()})
+	if s.loaded {
+		since := s.lastSync.Add(-syncSkew)
+		// Every clause has an index of its own, so the sync doesn't scan
+		// the collection
+		filter = bson.M{"$or": bson.A{
+			bson.M{"updated_at": bson.M{"$gt": since}},
+			bson.M{"published_at": bson.M{"$gt": since}},
+			bson.M{"deleted_at": bson.M{"$gt": since}},
+			// Set by changes that aren't edits, such as tag merges
+			bson.M{"index_changed_at": bson.M{"$gt": since}},
+		}}
+	}
+
+	opts := options.Find().SetProjection(bson.M{
+		"title":       1,
+		"description": 1,
+		"tags":        1,
+		"difficulty":  1,
+		"likes_count": 1,
+		"status":      1,
+		"deleted_at":  1,
+	})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var idea Idea
+		if err := cursor.Decode(&idea); err != nil {
+			return err
+		}
+		s.apply(&idea)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	if !s.loaded {
+		s.loaded = true
+		s.lastReconcile = started
+	} else if started.Sub(s.lastReconcile) >= reconcileInterval {
+		if err := s.reconcile(ctx, collection); err != nil {
+			return err
+		}
+		s.lastReconcile = started
+	}
+	s.lastSync = started
+	return nil
+}
+
+// reconcile re-reads the like counts autocomplete ranks by and drops ideas
+// that are no longer listed, such as ideas purged from the trash
+func (s *ideaIndexes) reconcile(ctx context.Context, collection *mongo.Collection) error {
+	// Ideas indexed after this snapshot may be missing from the query below
+	// and must be kept
+	indexed := s.similar.IDs()
+
+	cursor, err := collection.Find(ctx,
+		Live(bson.M{"status": listedStatuses()}),
+		options.Find().SetProjection(bson.M{"likes_count": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	listed := make(map[string]bool, len(indexed))
+	for cursor.Next(ctx) {
+		var idea Idea
+		if err := cursor.Decode(&idea); err != nil {
+			return err
+		}
+		listed[idea.ID.Hex()] = true
+		s.suggest.SetLikes(idea.ID.Hex(), idea.LikesCount)
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range indexed {
+		if !listed[id] {
+			s.similar.Remove(id)
+			s.suggest.Remove(id)
+		}
+	}
+	return nil
+}
+
+// ensureLoaded runs the first Sync if the periodic sync hasn't yet
+func (s *ideaIndexes) ensureLoaded(ctx context.Context) error {
+	s.syncMu.Lock()
+	loaded := s.loaded
+	s.syncMu.Unlock()
+	if loaded {
+		return nil
+	}
+	return s.Sync(ctx)
+}
+
+// Refresh re-reads one idea after a handler changed it, so this instance
+// doesn't wait for the next sync. Failures are logged.
+func (s *ideaIndexes) Refresh(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID) {
+	var idea Idea
+	err := db.Collection("ideas").FindOne(ctx, bson.M{"_id": ideaID}).Decode(&idea)
+	if err == mongo.ErrNoDocuments {
+		s.similar.Remove(ideaID.Hex())
+		s.suggest.Remove(ideaID.Hex())
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to refresh idea %s in the in-memory indexes: %v", ideaID.Hex(), err)
+		return
+	}
+	s.apply(&idea)
+}
+
+// SyncIndexes keeps the similar ideas and autocomplete indexes up to date
+// with changes made by other instances and background jobs until ctx is
+// cancelled
+func (h *Handler) SyncIndexes(ctx context.Context, interval time.Duration) {
+	runPeriodically(ctx, interval, func(ctx context.Context) {
+		if err := h.indexes.Sync(ctx); err != nil {
+			log.Printf("Failed to sync in-memory indexes: %v", err)
+		}
+	})
+}
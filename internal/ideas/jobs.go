@@ -0,0 +1,22 @@
+package ideas
+
+import (
+	"context"
+	"time"
+)
+
+// runPeriodically runs job straight away and then every interval until ctx is cancelled
+func runPeriodically(ctx context.Context, interval time.Duration, job func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
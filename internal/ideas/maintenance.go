@@ -0,0 +1,84 @@
+package ideas
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// hasUniqueBookmarkIndex reports whether the unique user_id+idea_id
+// bookmark index has been built
+func hasUniqueBookmarkIndex(ctx context.Context, db *mongo.Database) (bool, error) {
+	specs, err := db.Collection("bookmarks").Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, spec := range specs {
+		if spec.Name == "user_id_1_idea_id_1" && spec.Unique != nil && *spec.Unique {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RemoveDuplicateBookmarks collapses bookmarks sharing a user_id and idea_id
+// into the oldest one. Collections from the removed copies are merged into
+// the survivor and its note is kept, or taken from a copy if it has none.
+// It returns the number of bookmarks deleted.
+func RemoveDuplicateBookmarks(ctx context.Context, db *mongo.Database) (int64, error) {
+	coll := db.Collection("bookmarks")
+
+	cursor, err := coll.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "user_id", Value: "$user_id"}, {Key: "idea_id", Value: "$idea_id"}}},
+			{Key: "bookmarks", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var removed int64
+	for cursor.Next(ctx) {
+		var group struct {
+			Bookmarks []Bookmark `bson:"bookmarks"`
+		}
+		if err := cursor.Decode(&group); err != nil {
+			return removed, err
+		}
+
+		keep := group.Bookmarks[0]
+		collectionIDs := append([]bson.ObjectID{}, keep.CollectionIDs...)
+		note := keep.Note
+		dupIDs := make([]bson.ObjectID, 0, len(group.Bookmarks)-1)
+		for _, dup := range group.Bookmarks[1:] {
+			dupIDs = append(dupIDs, dup.ID)
+			collectionIDs = append(collectionIDs, dup.CollectionIDs...)
+			if note == "" {
+				note = dup.Note
+			}
+		}
+
+		update := bson.M{"$set": bson.M{"note": note}}
+		if len(collectionIDs) > 0 {
+			update["$addToSet"] = bson.M{"collection_ids": bson.M{"$each": collectionIDs}}
+		}
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": keep.ID}, update); err != nil {
+			return removed, err
+		}
+
+		result, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": dupIDs}})
+		if err != nil {
+			return removed, err
+		}
+		removed += result.DeletedCount
+	}
+
+	return removed, cursor.Err()
+}
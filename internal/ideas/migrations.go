@@ -0,0 +1,232 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// migrationLease is how long a migration claimed by an instance is left
+	// to it before another instance may take it over
+	migrationLease = time.Hour
+	// migrationRetryInterval is how often pending migrations are retried
+	// after one failed or was running on another instance
+	migrationRetryInterval = 5 * time.Minute
+)
+
+// migration is a one-off rewrite of stored data. Its job_state document
+// records that it completed, so it runs once per database. Migrations must
+// be safe to run again, since an instance can stop halfway through one.
+type migration struct {
+	id  string
+	run func(ctx context.Context, db *mongo.Database) (int64, error)
+}
+
+// migrations run in order: one only starts once every earlier one completed
+var migrations = []migration{
+	{id: "recount_forks", run: recountAllForks},
+	{id: "like_object_ids", run: convertLikeIdeaIDs},
+	{id: "recount_likes", run: recountAllLikes},
+	{id: "normalize_tags", run: normalizeTags},
+	{id: "backfill_published_at", run: backfillPublishedAt},
+}
+
+// Migrator applies pending data migrations in the background, off the
+// startup and request paths
+type Migrator struct {
+	client *mongo.Client
+	done   bool
+}
+
+// NewMigrator creates a migrator
+func NewMigrator(client *mongo.Client) *Migrator {
+	return &Migrator{client: client}
+}
+
+// Run applies pending migrations until they all completed or ctx is cancelled
+func (m *Migrator) Run(ctx context.Context) {
+	runPeriodically(ctx, migrationRetryInterval, func(ctx context.Context) {
+		if !m.done {
+			m.done = m.runPending(ctx)
+		}
+	})
+}
+
+// runPending applies in order every migration not yet completed. It stops at
+// the first one that fails or that another instance is running, leaving the
+// rest to a later run, and reports whether all of them completed.
+func (m *Migrator) runPending(ctx context.Context) bool {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("Failed to run migrations: %v", err)
+		return false
+	}
+
+	db := m.client.Database(cfg.MongoDBConfig.Database)
+	for _, mig := range migrations {
+		claimed, err := claimMigration(ctx, db, mig.id)
+		if err != nil {
+			log.Printf("Failed to claim migration %s: %v", mig.id, err)
+			return false
+		}
+		if !claimed {
+			completed, err := migrationCompleted(ctx, db, mig.id)
+			if err != nil {
+				log.Printf("Failed to check migration %s: %v", mig.id, err)
+				return false
+			}
+			if !completed {
+				log.Printf("Migration %s is running on another instance", mig.id)
+				return false
+			}
+			continue
+		}
+
+		affected, err := mig.run(ctx, db)
+		if err != nil {
+			log.Printf("Migration %s failed: %v", mig.id, err)
+			return false
+		}
+		if _, err := db.Collection("job_state").UpdateOne(ctx,
+			bson.M{"_id": migrationJobID(mig.id)},
+			bson.M{"$set": bson.M{"completed_at": time.Now(), "affected": affected}},
+		); err != nil {
+			log.Printf("Failed to record migration %s: %v", mig.id, err)
+			return false
+		}
+		log.Printf("Migration %s completed, %d documents affected", mig.id, affected)
+	}
+	return true
+}
+
+func migrationJobID(id string) string {
+	return "migration:" + id
+}
+
+// claimMigration reserves a migration for this instance. It fails to claim
+// migrations that completed or that another instance is still running.
+func claimMigration(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+	now := time.Now()
+	_, err := db.Collection("job_state").UpdateOne(ctx,
+		bson.M{
+			"_id":          migrationJobID(id),
+			"completed_at": bson.M{"$exists": false},
+			"started_at":   bson.M{"$lt": now.Add(-migrationLease)},
+		},
+		bson.M{"$set": bson.M{"started_at": now}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	// The upsert collides with the existing document when the filter doesn't match it
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// migrationCompleted reports whether a migration was recorded as completed
+func migrationCompleted(ctx context.Context, db *mongo.Database, id string) (bool, error) {
+	n, err := db.Collection("job_state").CountDocuments(ctx, bson.M{
+		"_id":          migrationJobID(id),
+		"completed_at": bson.M{"$exists": true},
+	})
+	return n > 0, err
+}
+
+// recountAllForks recounts the forks of every forked idea. Forks used to be
+// counted when created, including drafts that were never published.
+func recountAllForks(ctx context.Context, db *mongo.Database) (int64, error) {
+	var sources []bson.ObjectID
+	if err := db.Collection("ideas").Distinct(ctx, "forked_from", bson.M{}).Decode(&sources); err != nil {
+		return 0, err
+	}
+	return int64(len(sources)), recountForks(ctx, db, sources...)
+}
+
+// convertLikeIdeaIDs stores the idea_id of likes as an ObjectID. LikeIdea
+// used to store the hex string, which never matched an idea, so likes_count
+// was never updated.
+func convertLikeIdeaIDs(ctx context.Context, db *mongo.Database) (int64, error) {
+	likes := db.Collection("likes")
+	cursor, err := likes.Find(ctx, bson.M{"idea_id": bson.M{"$type": "string"}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var converted int64
+	for cursor.Next(ctx) {
+		var like struct {
+			ID     bson.ObjectID `bson:"_id"`
+			IdeaID string        `bson:"idea_id"`
+		}
+		if err := cursor.Decode(&like); err != nil {
+			return converted, err
+		}
+
+		ideaID, err := bson.ObjectIDFromHex(like.IdeaID)
+		if err != nil {
+			// Likes of IDs that were never valid can't belong to an idea
+			_, err = likes.DeleteOne(ctx, bson.M{"_id": like.ID})
+		} else {
+			_, err = likes.UpdateOne(ctx, bson.M{"_id": like.ID}, bson.M{"$set": bson.M{"idea_id": ideaID}})
+			// The user also liked the idea by its ObjectID, so this copy is redundant
+			if mongo.IsDuplicateKeyError(err) {
+				_, err = likes.DeleteOne(ctx, bson.M{"_id": like.ID})
+			}
+		}
+		if err != nil {
+			return converted, err
+		}
+		converted++
+	}
+	return converted, cursor.Err()
+}
+
+// recountAllLikes sets the likes_count of every idea to its number of likes
+func recountAllLikes(ctx context.Context, db *mongo.Database) (int64, error) {
+	cursor, err := db.Collection("ideas").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "likes",
+			"localField":   "_id",
+			"foreignField": "idea_id",
+			"pipeline":     mongo.Pipeline{{{Key: "$count", Value: "n"}}},
+			"as":           "likes",
+		}}},
+		{{Key: "$project", Value: bson.M{"likes_count": bson.M{"$ifNull": bson.A{bson.M{"$first": "$likes.n"}, 0}}}}},
+		{{Key: "$merge", Value: bson.M{"into": "ideas", "on": "_id", "whenMatched": "merge", "whenNotMatched": "discard"}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return 0, err
+	}
+	if err := cursor.Close(ctx); err != nil {
+		return 0, err
+	}
+	return db.Collection("ideas").CountDocuments(ctx, bson.M{})
+}
+
+// normalizeTags registers the tags of ideas tagged before the taxonomy
+// existed and rewrites them to their canonical slugs
+func normalizeTags(ctx context.Context, db *mongo.Database) (int64, error) {
+	return newTaxonomy(db.Client()).normalizeStoredTags(ctx, db)
+}
+
+// backfillPublishedAt sets published_at from created_at on ideas that were
+// listed before publish dates were recorded, so feeds can sort on it alone.
+// Drafts keep none until they are first published.
+func backfillPublishedAt(ctx context.Context, db *mongo.Database) (int64, error) {
+	result, err := db.Collection("ideas").UpdateMany(ctx,
+		bson.M{"published_at": bson.M{"$exists": false}, "status": bson.M{"$ne": StatusDraft}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"published_at": "$created_at"}}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,417 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// RevisionSnapshot is the editable state of an idea at a point in time
+type RevisionSnapshot struct {
+	Title       string       `bson:"title" json:"title"`
+	Description string       `bson:"description" json:"description"`
+	Tags        []string     `bson:"tags" json:"tags"`
+	Difficulty  string       `bson:"difficulty" json:"difficulty"`
+	Details     *IdeaDetails `bson:"details,omitempty" json:"details,omitempty"`
+}
+
+// Revision is a numbered snapshot of an idea recorded on every edit
+type Revision struct {
+	ID           bson.ObjectID    `bson:"_id,omitempty" json:"id"`
+	IdeaID       bson.ObjectID    `bson:"idea_id" json:"idea_id"`
+	Number       int              `bson:"number" json:"number"`
+	EditorID     string           `bson:"editor_id" json:"editor_id"`
+	RestoredFrom *int             `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+	Snapshot     RevisionSnapshot `bson:"snapshot" json:"snapshot"`
+	CreatedAt    time.Time        `bson:"created_at" json:"created_at"`
+}
+
+// FieldChange describes how a single field differs between two revisions.
+// Added and Removed are set for list fields.
+type FieldChange struct {
+	Field   string      `json:"field"`
+	From    interface{} `json:"from"`
+	To      interface{} `json:"to"`
+	Added   []string    `json:"added,omitempty"`
+	Removed []string    `json:"removed,omitempty"`
+}
+
+// loadSnapshot reads the current editable state of an idea
+func loadSnapshot(ctx context.Context, db *mongo.Database, idea *Idea) (RevisionSnapshot, error) {
+	snap := RevisionSnapshot{
+		Title:       idea.Title,
+		Description: idea.Description,
+		Tags:        idea.Tags,
+		Difficulty:  idea.Difficulty,
+	}
+
+	var details IdeaDetails
+	err := db.Collection("idea_details").FindOne(ctx, bson.M{"idea_id": idea.ID}).Decode(&details)
+	if err == nil {
+		snap.Details = &details
+	} else if err != mongo.ErrNoDocuments {
+		return snap, err
+	}
+	return snap, nil
+}
+
+// saveRevision stores snap as the idea's next revision number
+func saveRevision(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID, editorID string, snap RevisionSnapshot, createdAt time.Time, restoredFrom *int) (*Revision, error) {
+	var counter struct {
+		RevisionCount int `bson:"revision_count"`
+	}
+	err := db.Collection("ideas").FindOneAndUpdate(ctx,
+		bson.M{"_id": ideaID},
+		bson.M{"$inc": bson.M{"revision_count": 1}},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"revision_count": 1}),
+	).Decode(&counter)
+	if err != nil {
+		return nil, err
+	}
+
+	rev := &Revision{
+		IdeaID:       ideaID,
+		Number:       counter.RevisionCount,
+		EditorID:     editorID,
+		RestoredFrom: restoredFrom,
+		Snapshot:     snap,
+		CreatedAt:    createdAt,
+	}
+	result, err := db.Collection("idea_revisions").InsertOne(ctx, rev)
+	if err != nil {
+		return nil, err
+	}
+	rev.ID = result.InsertedID.(bson.ObjectID)
+	return rev, nil
+}
+
+// ensureBaselineRevision records the pre-edit state of an idea that has never
+// been revised, so the original version isn't lost on its first edit
+func ensureBaselineRevision(ctx context.Context, db *mongo.Database, idea *Idea, before RevisionSnapshot) error {
+	if idea.RevisionCount > 0 {
+		return nil
+	}
+	createdAt := idea.UpdatedAt
+	if createdAt.IsZero() {
+		createdAt = idea.CreatedAt
+	}
+	_, err := saveRevision(ctx, db, idea.ID, idea.AuthorID, before, createdAt, nil)
+	return err
+}
+
+// recordEdit snapshots an idea before and after an edit applied by apply.
+// Nothing is recorded if apply fails, and no revision is returned if the edit
+// changed nothing.
+func recordEdit(ctx context.Context, db *mongo.Database, idea *Idea, editorID string, restoredFrom *int, apply func() error) (*Revision, error) {
+	before, err := loadSnapshot(ctx, db, idea)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := apply(); err != nil {
+		return nil, err
+	}
+
+	var updated Idea
+	if err := db.Collection("ideas").FindOne(ctx, bson.M{"_id": idea.ID}).Decode(&updated); err != nil {
+		return nil, err
+	}
+	after, err := loadSnapshot(ctx, db, &updated)
+	if err != nil {
+		return nil, err
+	}
+	if len(diffSnapshots(before, after)) == 0 {
+		return nil, nil
+	}
+
+	if err := ensureBaselineRevision(ctx, db, idea, before); err != nil {
+		return nil, err
+	}
+	return saveRevision(ctx, db, idea.ID, editorID, after, time.Now(), restoredFrom)
+}
+
+// latestRevision loads the most recent revision of an idea
+func latestRevision(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID) (*Revision, error) {
+	var rev Revision
+	err := db.Collection("idea_revisions").FindOne(ctx,
+		bson.M{"idea_id": ideaID},
+		options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}}),
+	).Decode(&rev)
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// findRevision loads a single revision of an idea by number
+func findRevision(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID, number string) (*Revision, error) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 {
+		return nil, apperror.Validation("invalid_revision", "Invalid revision number", apperror.FieldError{
+			Field:   "rev",
+			Message: "must be a positive integer",
+		})
+	}
+
+	var rev Revision
+	err = db.Collection("idea_revisions").FindOne(ctx, bson.M{"idea_id": ideaID, "number": n}).Decode(&rev)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("revision_not_found", "Revision not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_revision_failed", "Failed to fetch revision", err)
+	}
+	return &rev, nil
+}
+
+// diffSnapshots compares two snapshots field by field
+func diffSnapshots(from, to RevisionSnapshot) []FieldChange {
+	changes := []FieldChange{}
+	scalar := func(field string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changes = append(changes, FieldChange{Field: field, From: a, To: b})
+		}
+	}
+	list := func(field string, a, b []string) {
+		if len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b) {
+			return
+		}
+		added, removed := diffStrings(a, b)
+		changes = append(changes, FieldChange{Field: field, From: a, To: b, Added: added, Removed: removed})
+	}
+
+	scalar("title", from.Title, to.Title)
+	scalar("description", from.Description, to.Description)
+	list("tags", from.Tags, to.Tags)
+	scalar("difficulty", from.Difficulty, to.Difficulty)
+
+	var a, b IdeaDetails
+	if from.Details != nil {
+		a = *from.Details
+	}
+	if to.Details != nil {
+		b = *to.Details
+	}
+	normalizeDetails(&a)
+	normalizeDetails(&b)
+	list("details.requirements", a.Requirements, b.Requirements)
+	list("details.tech_stack", a.TechStack, b.TechStack)
+	scalar("details.milestones", a.Milestones, b.Milestones)
+	list("details.learning_outcomes", a.LearningOutcomes, b.LearningOutcomes)
+	scalar("details.estimated_hours", a.EstimatedHours, b.EstimatedHours)
+	scalar("details.resources", a.Resources, b.Resources)
+
+	return changes
+}
+
+// diffStrings returns the values only in b (added) and only in a (removed)
+func diffStrings(a, b []string) (added, removed []string) {
+	inA := make(map[string]bool, len(a))
+	for _, v := range a {
+		inA[v] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, v := range b {
+		inB[v] = true
+		if !inA[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range a {
+		if !inB[v] {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
+
+// GetRevisions lists an idea's revisions, newest first
+func (h *Handler) GetRevisions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if _, err := findVisibleIdea(ctx, c, db, ideaID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	collection := db.Collection("idea_revisions")
+	filter := bson.M{"idea_id": ideaID}
+
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_revisions_failed", "Failed to count revisions", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "number", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_revisions_failed", "Failed to fetch revisions", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []Revision
+	if err := cursor.All(ctx, &revisions); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_revisions_failed", "Failed to decode revisions", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       revisions,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// DiffRevisions returns the field-level changes between revisions from and to
+func (h *Handler) DiffRevisions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if _, err := findVisibleIdea(ctx, c, db, ideaID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	from, err := findRevision(ctx, db, ideaID, c.Query("from"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	to, err := findRevision(ctx, db, ideaID, c.Query("to"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"from":    from.Number,
+			"to":      to.Number,
+			"changes": diffSnapshots(from.Snapshot, to.Snapshot),
+		},
+	})
+}
+
+// RestoreRevision makes an older revision current again, recorded as a new revision
+func (h *Handler) RestoreRevision(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	idea, err := findAuthoredIdea(ctx, db, ideaID, userID, isAdmin(c))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	target, err := findRevision(ctx, db, ideaID, c.Param("rev"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	snap := target.Snapshot
+	// Tags merged since the revision was made are restored as their survivor
+	snap.Tags, err = h.taxonomy.normalize(ctx, db, snap.Tags)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("normalize_tags_failed", "Failed to normalize tags", err))
+		return
+	}
+	rev, err := recordEdit(ctx, db, idea, userID, &target.Number, func() error {
+		now := time.Now()
+		_, err := db.Collection("ideas").UpdateOne(ctx, bson.M{"_id": ideaID}, bson.M{"$set": bson.M{
+			"title":       snap.Title,
+			"description": snap.Description,
+			"tags":        snap.Tags,
+			"difficulty":  snap.Difficulty,
+			"updated_at":  now,
+		}})
+		if err != nil {
+			return err
+		}
+
+		if snap.Details == nil {
+			_, err = db.Collection("idea_details").DeleteOne(ctx, bson.M{"idea_id": ideaID})
+			return err
+		}
+		details := *snap.Details
+		details.UpdatedAt = now
+		_, err = db.Collection("idea_details").UpdateOne(ctx,
+			bson.M{"idea_id": ideaID},
+			bson.M{"$set": details},
+			options.UpdateOne().SetUpsert(true),
+		)
+		return err
+	})
+	if err == nil && rev == nil {
+		// The idea already matches the revision
+		rev, err = latestRevision(ctx, db, ideaID)
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("restore_revision_failed", "Failed to restore revision", err))
+		return
+	}
+	h.indexes.Refresh(ctx, db, ideaID)
+
+	c.JSON(http.StatusOK, gin.H{"data": rev})
+}
@@ -0,0 +1,719 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// maxSavedSearches caps how many searches a user can save
+	maxSavedSearches = 25
+	// matchLookback re-checks ideas published this far before the last run,
+	// covering scheduled drafts published with their earlier publish_at
+	matchLookback = 5 * time.Minute
+	// matchBatch is how many newly published ideas are matched per query
+	matchBatch = 500
+	// matcherJobID is the job_state document holding the matcher's checkpoint
+	matcherJobID = "saved_search_matcher"
+)
+
+// NotificationSavedSearchMatch is the type of notifications about a newly
+// published idea matching a saved search
+const NotificationSavedSearchMatch = "saved_search_match"
+
+// SavedSearch is a named idea listing query whose new matches the user is
+// notified about
+type SavedSearch struct {
+	ID           bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID       string        `bson:"user_id" json:"user_id"`
+	Name         string        `bson:"name" json:"name"`
+	Tags         []string      `bson:"tags,omitempty" json:"tags,omitempty"`
+	Difficulty   string        `bson:"difficulty,omitempty" json:"difficulty,omitempty"`
+	Search       string        `bson:"search,omitempty" json:"search,omitempty"`
+	LastViewedAt time.Time     `bson:"last_viewed_at" json:"last_viewed_at"`
+	NewCount     int64         `bson:"-" json:"new_count"`
+	CreatedAt    time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+// Notification tells a user about something that happened, such as a new
+// idea matching one of their saved searches
+type Notification struct {
+	ID              bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string        `bson:"user_id" json:"user_id"`
+	Type            string        `bson:"type" json:"type"`
+	SavedSearchID   bson.ObjectID `bson:"saved_search_id" json:"saved_search_id"`
+	SavedSearchName string        `bson:"saved_search_name" json:"saved_search_name"`
+	IdeaID          bson.ObjectID `bson:"idea_id" json:"idea_id"`
+	IdeaTitle       string        `bson:"idea_title" json:"idea_title"`
+	CreatedAt       time.Time     `bson:"created_at" json:"created_at"`
+	ReadAt          *time.Time    `bson:"read_at,omitempty" json:"read_at,omitempty"`
+}
+
+type savedSearchRequest struct {
+	Name       string   `json:"name" binding:"required,min=1,max=100"`
+	Tags       []string `json:"tags" binding:"max=20,dive,required,max=50"`
+	Difficulty string   `json:"difficulty" binding:"omitempty,oneof=beginner intermediate advanced"`
+	Search     string   `json:"search" binding:"max=500"`
+}
+
+type markNotificationsReadRequest struct {
+	IDs []string `json:"ids"`
+}
+
+func errInvalidSavedSearchID() *apperror.Error {
+	return apperror.Validation("invalid_saved_search_id", "Invalid saved search ID", apperror.FieldError{
+		Field:   "id",
+		Message: "must be a 24 character hex ObjectID",
+	})
+}
+
+func errSavedSearchNameTaken() *apperror.Error {
+	return apperror.Conflict("saved_search_name_taken", "A saved search with this name already exists")
+}
+
+// filter matches the listed ideas the saved search would list
+func (s *SavedSearch) filter(ctx context.Context, taxonomy *taxonomy) (bson.M, *apperror.Error) {
+	filter, _, err := listFilter(ctx, taxonomy, s.Tags, s.Difficulty, s.Search)
+	return filter, err
+}
+
+// bindSavedSearch reads and validates a saved search from the request body
+func (h *Handler) bindSavedSearch(ctx context.Context, c *gin.Context) (*SavedSearch, *apperror.Error) {
+	var req savedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, apperror.FromBinding(err)
+	}
+
+	saved := &SavedSearch{
+		Name:       strings.TrimSpace(req.Name),
+		Tags:       h.taxonomy.resolve(ctx, req.Tags),
+		Difficulty: req.Difficulty,
+		Search:     strings.TrimSpace(req.Search),
+	}
+	if len(saved.Tags) == 0 && saved.Difficulty == "" && saved.Search == "" {
+		return nil, apperror.Validation("empty_saved_search", "A saved search needs tags, a difficulty or search text", apperror.FieldError{
+			Field:   "search",
+			Message: "set at least one of tags, difficulty or search",
+		})
+	}
+	if _, err := saved.filter(ctx, h.taxonomy); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// countNew counts the notifications for saved since it was last viewed
+func countNew(ctx context.Context, db *mongo.Database, saved *SavedSearch) error {
+	n, err := db.Collection("notifications").CountDocuments(ctx, bson.M{
+		"saved_search_id": saved.ID,
+		"created_at":      bson.M{"$gt": saved.LastViewedAt},
+	})
+	saved.NewCount = n
+	return err
+}
+
+// findSavedSearch loads one of the caller's saved searches
+func findSavedSearch(ctx context.Context, c *gin.Context, db *mongo.Database) (*SavedSearch, error) {
+	id, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		return nil, errInvalidSavedSearchID()
+	}
+
+	var saved SavedSearch
+	err = db.Collection("saved_searches").FindOne(ctx, bson.M{"_id": id, "user_id": c.GetString("user_id")}).Decode(&saved)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("saved_search_not_found", "Saved search not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_saved_search_failed", "Failed to fetch saved search", err)
+	}
+	return &saved, nil
+}
+
+// ListSavedSearches returns the caller's saved searches, oldest first, with
+// how many new matches each has had since it was last viewed
+func (h *Handler) ListSavedSearches(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	cursor, err := db.Collection("saved_searches").Find(ctx,
+		bson.M{"user_id": c.GetString("user_id")},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_saved_searches_failed", "Failed to fetch saved searches", err))
+		return
+	}
+
+	searches := []SavedSearch{}
+	if err := cursor.All(ctx, &searches); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_saved_searches_failed", "Failed to decode saved searches", err))
+		return
+	}
+	for i := range searches {
+		if err := countNew(ctx, db, &searches[i]); err != nil {
+			apperror.Abort(c, apperror.Internal("count_notifications_failed", "Failed to count new matches", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": searches})
+}
+
+// CreateSavedSearch saves a listing query under a name
+func (h *Handler) CreateSavedSearch(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	saved, bindErr := h.bindSavedSearch(ctx, c)
+	if bindErr != nil {
+		apperror.Abort(c, bindErr)
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	coll := db.Collection("saved_searches")
+
+	count, err := coll.CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("create_saved_search_failed", "Failed to save search", err))
+		return
+	}
+	if count >= maxSavedSearches {
+		apperror.Abort(c, apperror.Conflict("saved_search_limit_reached", "You can save up to "+strconv.Itoa(maxSavedSearches)+" searches"))
+		return
+	}
+
+	now := time.Now()
+	saved.UserID = userID
+	saved.LastViewedAt = now
+	saved.CreatedAt = now
+	saved.UpdatedAt = now
+	result, err := coll.InsertOne(ctx, saved)
+	if mongo.IsDuplicateKeyError(err) {
+		apperror.Abort(c, errSavedSearchNameTaken())
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("create_saved_search_failed", "Failed to save search", err))
+		return
+	}
+	saved.ID = result.InsertedID.(bson.ObjectID)
+
+	c.JSON(http.StatusCreated, gin.H{"data": saved})
+}
+
+// GetSavedSearch returns one of the caller's saved searches
+func (h *Handler) GetSavedSearch(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	saved, err := findSavedSearch(ctx, c, db)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if err := countNew(ctx, db, saved); err != nil {
+		apperror.Abort(c, apperror.Internal("count_notifications_failed", "Failed to count new matches", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": saved})
+}
+
+// UpdateSavedSearch replaces the name and query of a saved search. Ideas
+// that matched the old query keep their notifications.
+func (h *Handler) UpdateSavedSearch(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	existing, err := findSavedSearch(ctx, c, db)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	saved, bindErr := h.bindSavedSearch(ctx, c)
+	if bindErr != nil {
+		apperror.Abort(c, bindErr)
+		return
+	}
+
+	set := bson.M{
+		"name":       saved.Name,
+		"updated_at": time.Now(),
+	}
+	unset := bson.M{}
+	if len(saved.Tags) > 0 {
+		set["tags"] = saved.Tags
+	} else {
+		unset["tags"] = ""
+	}
+	if saved.Difficulty != "" {
+		set["difficulty"] = saved.Difficulty
+	} else {
+		unset["difficulty"] = ""
+	}
+	if saved.Search != "" {
+		set["search"] = saved.Search
+	} else {
+		unset["search"] = ""
+	}
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	var updated SavedSearch
+	err = db.Collection("saved_searches").FindOneAndUpdate(ctx,
+		bson.M{"_id": existing.ID},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if mongo.IsDuplicateKeyError(err) {
+		apperror.Abort(c, errSavedSearchNameTaken())
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_saved_search_failed", "Failed to update saved search", err))
+		return
+	}
+	if err := countNew(ctx, db, &updated); err != nil {
+		apperror.Abort(c, apperror.Internal("count_notifications_failed", "Failed to count new matches", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// DeleteSavedSearch deletes a saved search and its notifications
+func (h *Handler) DeleteSavedSearch(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	saved, err := findSavedSearch(ctx, c, db)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if _, err := db.Collection("saved_searches").DeleteOne(ctx, bson.M{"_id": saved.ID}); err != nil {
+		apperror.Abort(c, apperror.Internal("delete_saved_search_failed", "Failed to delete saved search", err))
+		return
+	}
+	if _, err := db.Collection("notifications").DeleteMany(ctx, bson.M{"saved_search_id": saved.ID}); err != nil {
+		apperror.Abort(c, apperror.Internal("delete_notifications_failed", "Failed to delete notifications", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
+
+// GetSavedSearchIdeas runs a saved search, newest first, and marks it as
+// viewed. The response reports how many matches were new since the last view.
+func (h *Handler) GetSavedSearchIdeas(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	saved, err := findSavedSearch(ctx, c, db)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if err := countNew(ctx, db, saved); err != nil {
+		apperror.Abort(c, apperror.Internal("count_notifications_failed", "Failed to count new matches", err))
+		return
+	}
+
+	filter, query, filterErr := listFilter(ctx, h.taxonomy, saved.Tags, saved.Difficulty, saved.Search)
+	if filterErr != nil {
+		apperror.Abort(c, filterErr)
+		return
+	}
+
+	collection := db.Collection("ideas")
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+
+	var ideas []ListedIdea
+	if err := cursor.All(ctx, &ideas); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+		return
+	}
+	refs := make([]*Idea, len(ideas))
+	for i := range ideas {
+		NormalizeStatus(&ideas[i].Idea)
+		refs[i] = &ideas[i].Idea
+	}
+	EmbedAuthors(ctx, db, refs...)
+	if query != nil {
+		highlight(ideas, query)
+	}
+
+	if _, err := db.Collection("saved_searches").UpdateOne(ctx,
+		bson.M{"_id": saved.ID},
+		bson.M{"$set": bson.M{"last_viewed_at": time.Now()}},
+	); err != nil {
+		apperror.Abort(c, apperror.Internal("update_saved_search_failed", "Failed to mark saved search as viewed", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       ideas,
+		"new_count":  saved.NewCount,
+		"new_since":  saved.LastViewedAt,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// ListNotifications returns the caller's notifications, newest first, with
+// the number still unread
+func (h *Handler) ListNotifications(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	filter := bson.M{"user_id": userID}
+	if unread := c.Query("unread"); unread != "" {
+		onlyUnread, err := strconv.ParseBool(unread)
+		if err != nil {
+			apperror.Abort(c, apperror.Validation("invalid_unread", "Invalid unread filter", apperror.FieldError{
+				Field:   "unread",
+				Message: "must be true or false",
+			}))
+			return
+		}
+		if onlyUnread {
+			filter["read_at"] = bson.M{"$exists": false}
+		}
+	}
+
+	coll := h.client.Database(cfg.MongoDBConfig.Database).Collection("notifications")
+	page, pageSize := pagination.Parse(c)
+
+	total, err := coll.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_notifications_failed", "Failed to count notifications", err))
+		return
+	}
+	unreadCount, err := coll.CountDocuments(ctx, bson.M{"user_id": userID, "read_at": bson.M{"$exists": false}})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_notifications_failed", "Failed to count notifications", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+	cursor, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_notifications_failed", "Failed to fetch notifications", err))
+		return
+	}
+
+	notifications := []Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_notifications_failed", "Failed to decode notifications", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         notifications,
+		"unread_count": unreadCount,
+		"pagination":   pagination.Meta(page, pageSize, total),
+	})
+}
+
+// MarkNotificationsRead marks the given notifications, or all of the
+// caller's notifications when no IDs are given, as read
+func (h *Handler) MarkNotificationsRead(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req markNotificationsReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apperror.Abort(c, apperror.FromBinding(err))
+			return
+		}
+	}
+
+	filter := bson.M{"user_id": c.GetString("user_id"), "read_at": bson.M{"$exists": false}}
+	if len(req.IDs) > 0 {
+		ids := make([]bson.ObjectID, 0, len(req.IDs))
+		for _, raw := range req.IDs {
+			id, err := bson.ObjectIDFromHex(raw)
+			if err != nil {
+				apperror.Abort(c, apperror.Validation("invalid_notification_id", "Invalid notification ID", apperror.FieldError{
+					Field:   "ids",
+					Message: raw + " is not a valid ObjectID",
+				}))
+				return
+			}
+			ids = append(ids, id)
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	result, err := h.client.Database(cfg.MongoDBConfig.Database).Collection("notifications").UpdateMany(ctx,
+		filter,
+		bson.M{"$set": bson.M{"read_at": time.Now()}},
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("mark_notifications_read_failed", "Failed to mark notifications as read", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"marked": result.ModifiedCount}})
+}
+
+// SavedSearchMatcher periodically matches newly published ideas against
+// every saved search and records a notification for each match
+type SavedSearchMatcher struct {
+	client   *mongo.Client
+	taxonomy *taxonomy
+	interval time.Duration
+}
+
+// NewSavedSearchMatcher creates a matcher that runs every interval
+func NewSavedSearchMatcher(client *mongo.Client, interval time.Duration) *SavedSearchMatcher {
+	return &SavedSearchMatcher{
+		client:   client,
+		taxonomy: newTaxonomy(client),
+		interval: interval,
+	}
+}
+
+// Run matches new ideas until ctx is cancelled
+func (m *SavedSearchMatcher) Run(ctx context.Context) {
+	runPeriodically(ctx, m.interval, func(ctx context.Context) {
+		notified, err := m.MatchNew(ctx)
+		if err != nil {
+			log.Printf("Failed to match saved searches: %v", err)
+		} else if notified > 0 {
+			log.Printf("Recorded %d saved search notifications", notified)
+		}
+	})
+}
+
+// MatchNew matches ideas published since the last run against every saved
+// search. The first run only records a checkpoint, so existing ideas don't
+// notify anyone. Notifications are upserted against a unique index, so
+// overlapping runs and several instances never notify twice.
+func (m *SavedSearchMatcher) MatchNew(ctx context.Context) (int64, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	db := m.client.Database(cfg.MongoDBConfig.Database)
+	jobs := db.Collection("job_state")
+	started := time.Now()
+
+	var state struct {
+		Checkpoint time.Time `bson:"checkpoint"`
+	}
+	err = jobs.FindOne(ctx, bson.M{"_id": matcherJobID}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		_, err = jobs.UpdateOne(ctx,
+			bson.M{"_id": matcherJobID},
+			bson.M{"$setOnInsert": bson.M{"checkpoint": started}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var notified int64
+	filter := Live(bson.M{
+		"status":       listedStatuses(),
+		"published_at": bson.M{"$gt": state.Checkpoint.Add(-matchLookback), "$lte": started},
+	})
+	for {
+		cursor, err := db.Collection("ideas").Find(ctx,
+			filter,
+			options.Find().
+				SetProjection(bson.M{"title": 1, "published_at": 1}).
+				SetSort(bson.D{{Key: "published_at", Value: 1}, {Key: "_id", Value: 1}}).
+				SetLimit(matchBatch),
+		)
+		if err != nil {
+			return notified, err
+		}
+		var batch []Idea
+		if err := cursor.All(ctx, &batch); err != nil {
+			return notified, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		n, err := m.matchBatch(ctx, db, batch)
+		notified += n
+		if err != nil {
+			return notified, err
+		}
+		if len(batch) < matchBatch {
+			break
+		}
+
+		// Continue after the last idea of the batch
+		last := batch[len(batch)-1]
+		filter["$or"] = bson.A{
+			bson.M{"published_at": bson.M{"$gt": *last.PublishedAt, "$lte": started}},
+			bson.M{"published_at": *last.PublishedAt, "_id": bson.M{"$gt": last.ID}},
+		}
+		delete(filter, "published_at")
+	}
+
+	_, err = jobs.UpdateOne(ctx,
+		bson.M{"_id": matcherJobID},
+		bson.M{"$max": bson.M{"checkpoint": started}},
+	)
+	return notified, err
+}
+
+// matchBatch runs every saved search restricted to the ideas in batch
+func (m *SavedSearchMatcher) matchBatch(ctx context.Context, db *mongo.Database, batch []Idea) (int64, error) {
+	ids := make([]bson.ObjectID, len(batch))
+	titles := make(map[bson.ObjectID]string, len(batch))
+	for i, idea := range batch {
+		ids[i] = idea.ID
+		titles[idea.ID] = idea.Title
+	}
+
+	cursor, err := db.Collection("saved_searches").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var notified int64
+	for cursor.Next(ctx) {
+		var saved SavedSearch
+		if err := cursor.Decode(&saved); err != nil {
+			return notified, err
+		}
+		filter, filterErr := saved.filter(ctx, m.taxonomy)
+		if filterErr != nil {
+			log.Printf("Skipping invalid saved search %s: %v", saved.ID.Hex(), filterErr)
+			continue
+		}
+		filter["_id"] = bson.M{"$in": ids}
+		// Ideas published before the search was saved, or by its owner, aren't news
+		filter["published_at"] = bson.M{"$gte": saved.CreatedAt}
+		filter["author_id"] = bson.M{"$ne": saved.UserID}
+
+		var matched []bson.ObjectID
+		if err := db.Collection("ideas").Distinct(ctx, "_id", filter).Decode(&matched); err != nil {
+			return notified, err
+		}
+		for _, ideaID := range matched {
+			result, err := db.Collection("notifications").UpdateOne(ctx,
+				bson.M{"saved_search_id": saved.ID, "idea_id": ideaID},
+				bson.M{"$setOnInsert": bson.M{
+					"user_id":           saved.UserID,
+					"type":              NotificationSavedSearchMatch,
+					"saved_search_name": saved.Name,
+					"idea_title":        titles[ideaID],
+					"created_at":        time.Now(),
+				}},
+				options.UpdateOne().SetUpsert(true),
+			)
+			if err != nil {
+				return notified, err
+			}
+			notified += result.UpsertedCount
+		}
+	}
+	return notified, cursor.Err()
+}
@@ -0,0 +1,151 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/search"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	// titleHighlightWidth fits any title, so titles are never cut
+	titleHighlightWidth = 200
+	// descriptionHighlightWidth is the length of description snippets
+	descriptionHighlightWidth = 160
+)
+
+// ListedIdea is an idea in a listing. Search results also carry their text
+// search score and highlighted matches.
+type ListedIdea struct {
+	Idea       `bson:",inline"`
+	TextScore  float64     `bson:"text_score,omitempty" json:"text_score,omitempty"`
+	Highlights *Highlights `bson:"-" json:"highlights,omitempty"`
+}
+
+// Highlights are the title and a description snippet of a search result,
+// HTML-escaped with matched words wrapped in <mark>
+type Highlights struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+var validDifficulties = map[string]bool{
+	DifficultyBeginner:     true,
+	DifficultyIntermediate: true,
+	DifficultyAdvanced:     true,
+}
+
+// listFilter matches listed ideas having any of tags or their synonyms, with
+// difficulty if set, and matching the search query if set. query is nil
+// without a search.
+func listFilter(ctx context.Context, taxonomy *taxonomy, tags []string, difficulty, rawSearch string) (filter bson.M, query *search.Query, err *apperror.Error) {
+	filter = Live(bson.M{"status": listedStatuses()})
+	if len(tags) > 0 {
+		filter["tags"] = bson.M{"$in": taxonomy.expand(ctx, tags)}
+	}
+	if difficulty != "" {
+		filter["difficulty"] = difficulty
+	}
+	if rawSearch != "" {
+		var conditions bson.M
+		query, conditions, err = parseSearch(ctx, taxonomy, rawSearch)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Only $text and $and, which the filter doesn't otherwise use
+		for key, value := range conditions {
+			filter[key] = value
+		}
+	}
+	return filter, query, nil
+}
+
+// parseSearch parses the search query parameter into filter conditions
+// to merge into a listing's filter
+func parseSearch(ctx context.Context, taxonomy *taxonomy, raw string) (*search.Query, bson.M, *apperror.Error) {
+	query, err := search.Parse(raw)
+	if err != nil {
+		return nil, nil, errInvalidSearch(err.Error())
+	}
+
+	filter := bson.M{}
+	conditions := bson.A{}
+
+	if query.HasText() {
+		filter["$text"] = bson.M{"$search": query.TextSearch()}
+	} else {
+		// $text needs a word to match, so exclusions on their own are
+		// applied to title and description directly
+		excluded := append(append([]string{}, query.ExcludedTerms...), query.ExcludedPhrases...)
+		for _, text := range excluded {
+			pattern := `(?i)\b` + regexp.QuoteMeta(text) + `\b`
+			conditions = append(conditions, bson.M{"$nor": bson.A{
+				bson.M{"title": bson.M{"$regex": pattern}},
+				bson.M{"description": bson.M{"$regex": pattern}},
+			}})
+		}
+	}
+
+	// Every tag must match, by its slug or any of its synonyms
+	for _, tag := range query.Tags {
+		conditions = append(conditions, bson.M{"tags": bson.M{"$in": taxonomy.expand(ctx, []string{tag})}})
+	}
+	if len(query.ExcludedTags) > 0 {
+		conditions = append(conditions, bson.M{"tags": bson.M{"$nin": taxonomy.expand(ctx, query.ExcludedTags)}})
+	}
+
+	for _, d := range append(append([]string{}, query.Difficulties...), query.ExcludedDifficulties...) {
+		if !validDifficulties[d] {
+			return nil, nil, errInvalidSearch("difficulty: must be beginner, intermediate or advanced, got " + d)
+		}
+	}
+	if len(query.Difficulties) > 0 {
+		conditions = append(conditions, bson.M{"difficulty": bson.M{"$in": query.Difficulties}})
+	}
+	if len(query.ExcludedDifficulties) > 0 {
+		conditions = append(conditions, bson.M{"difficulty": bson.M{"$nin": query.ExcludedDifficulties}})
+	}
+
+	if len(query.Authors) > 0 {
+		conditions = append(conditions, bson.M{"author_id": bson.M{"$in": query.Authors}})
+	}
+	if len(query.ExcludedAuthors) > 0 {
+		conditions = append(conditions, bson.M{"author_id": bson.M{"$nin": query.ExcludedAuthors}})
+	}
+
+	if query.CreatedFrom != nil || query.CreatedTo != nil {
+		created := bson.M{}
+		if query.CreatedFrom != nil {
+			created["$gte"] = *query.CreatedFrom
+		}
+		if query.CreatedTo != nil {
+			created["$lt"] = *query.CreatedTo
+		}
+		conditions = append(conditions, bson.M{"created_at": created})
+	}
+
+	if len(conditions) > 0 {
+		filter["$and"] = conditions
+	}
+	return query, filter, nil
+}
+
+func errInvalidSearch(message string) *apperror.Error {
+	return apperror.Validation("invalid_search", "Invalid search query", apperror.FieldError{
+		Field:   "search",
+		Message: message,
+	})
+}
+
+// highlight fills in the highlights of search results
+func highlight(ideas []ListedIdea, query *search.Query) {
+	terms := query.HighlightTerms()
+	for i := range ideas {
+		ideas[i].Highlights = &Highlights{
+			Title:       search.Highlight(ideas[i].Title, terms, titleHighlightWidth),
+			Description: search.Highlight(ideas[i].Description, terms, descriptionHighlightWidth),
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/similarity"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	// similarTopN is how many related ideas are cached per idea
+	similarTopN = 20
+	// defaultSimilarLimit is how many related ideas GetSimilar returns by default
+	defaultSimilarLimit = 10
+)
+
+// SimilarIdea is an idea related to another, with how related it is
+type SimilarIdea struct {
+	Idea  `bson:",inline"`
+	Score float64 `json:"score"`
+}
+
+// similarityDocument is the part of an idea the index compares
+func similarityDocument(idea *Idea) similarity.Document {
+	return similarity.Document{
+		Title:       idea.Title,
+		Description: idea.Description,
+		Tags:        idea.Tags,
+		Difficulty:  idea.Difficulty,
+	}
+}
+
+// GetSimilar returns the ideas most related to an idea by text, tags and
+// difficulty, best first
+func (h *Handler) GetSimilar(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	limit := defaultSimilarLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > similarTopN {
+			apperror.Abort(c, apperror.Validation("invalid_limit", "Invalid limit", apperror.FieldError{
+				Field:   "limit",
+				Message: "must be between 1 and " + strconv.Itoa(similarTopN),
+			}))
+			return
+		}
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	idea, err := findVisibleIdea(ctx, c, db, ideaID)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if err := h.indexes.ensureLoaded(ctx); err != nil {
+		apperror.Abort(c, apperror.Internal("load_similarity_index_failed", "Failed to load similarity index", err))
+		return
+	}
+
+	// Drafts and archived ideas aren't indexed, so they are ranked on the fly
+	matches, ok := h.indexes.similar.Similar(idea.ID.Hex())
+	if !ok {
+		matches = h.indexes.similar.SimilarTo(similarityDocument(idea))
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	similar, err := loadMatches(ctx, db, matches)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+	refs := make([]*Idea, len(similar))
+	for i := range similar {
+		refs[i] = &similar[i].Idea
+	}
+	EmbedAuthors(ctx, db, refs...)
+
+	c.JSON(http.StatusOK, gin.H{"data": similar})
+}
+
+// loadMatches fetches the ideas in matches, keeping their order and skipping
+// ideas that stopped being listed since they were indexed
+func loadMatches(ctx context.Context, db *mongo.Database, matches []similarity.Match) ([]SimilarIdea, error) {
+	similar := []SimilarIdea{}
+	if len(matches) == 0 {
+		return similar, nil
+	}
+
+	ids := make([]bson.ObjectID, 0, len(matches))
+	for _, m := range matches {
+		if id, err := bson.ObjectIDFromHex(m.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	cursor, err := db.Collection("ideas").Find(ctx, Live(bson.M{
+		"_id":    bson.M{"$in": ids},
+		"status": listedStatuses(),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	var found []Idea
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Idea, len(found))
+	for _, idea := range found {
+		byID[idea.ID.Hex()] = idea
+	}
+	for _, m := range matches {
+		if idea, ok := byID[m.ID]; ok {
+			NormalizeStatus(&idea)
+			similar = append(similar, SimilarIdea{Idea: idea, Score: m.Score})
+		}
+	}
+	return similar, nil
+}
@@ -0,0 +1,336 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/metrics"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type setStatusRequest struct {
+	Status    string     `json:"status" binding:"required,oneof=draft published archived"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// checkPublishAt validates a scheduled publish time against the target status
+func checkPublishAt(status string, publishAt *time.Time, now time.Time) error {
+	if publishAt == nil {
+		return nil
+	}
+	if status != StatusDraft {
+		return apperror.Validation("invalid_publish_at", "Only drafts can be scheduled", apperror.FieldError{
+			Field:   "publish_at",
+			Message: "requires status draft",
+		})
+	}
+	if !publishAt.After(now) {
+		return apperror.Validation("invalid_publish_at", "Publish time must be in the future", apperror.FieldError{
+			Field:   "publish_at",
+			Message: "must be in the future",
+		})
+	}
+	return nil
+}
+
+// findVisibleIdea loads an idea, hiding drafts from everyone but their author and admins
+func findVisibleIdea(ctx context.Context, c *gin.Context, db *mongo.Database, ideaID bson.ObjectID) (*Idea, error) {
+	var idea Idea
+	err := db.Collection("ideas").FindOne(ctx, Live(bson.M{"_id": ideaID})).Decode(&idea)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("idea_not_found", "Idea not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_idea_failed", "Failed to fetch idea", err)
+	}
+	if idea.Status == StatusDraft && idea.AuthorID != c.GetString("user_id") && !isAdmin(c) {
+		return nil, apperror.NotFound("idea_not_found", "Idea not found")
+	}
+	NormalizeStatus(&idea)
+	return &idea, nil
+}
+
+// CreateIdea stores a new idea authored by the caller, as a draft unless
+// published straight away. Likely duplicates are returned alongside it.
+func (h *Handler) CreateIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req createIdeaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	if req.Status == "" {
+		req.Status = StatusDraft
+	}
+
+	now := time.Now()
+	if err := checkPublishAt(req.Status, req.PublishAt, now); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	tags, err := h.taxonomy.normalize(ctx, db, req.Tags)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("normalize_tags_failed", "Failed to normalize tags", err))
+		return
+	}
+
+	idea := Idea{
+		ID:          bson.NewObjectID(),
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		Tags:        tags,
+		Difficulty:  req.Difficulty,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		AuthorID:    c.GetString("user_id"),
+		Status:      req.Status,
+		PublishAt:   req.PublishAt,
+	}
+	if idea.Status == StatusPublished {
+		idea.PublishedAt = &now
+	}
+
+	if _, err := db.Collection("ideas").InsertOne(ctx, idea); err != nil {
+		apperror.Abort(c, apperror.Internal("create_idea_failed", "Failed to create idea", err))
+		return
+	}
+	if idea.Status == StatusPublished {
+		metrics.IdeasCreated.Inc()
+	}
+	h.indexes.apply(&idea)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data":       idea,
+		"duplicates": duplicatesOf(ctx, db, &idea),
+	})
+}
+
+// SetStatus publishes, archives or moves an idea back to draft. A draft with
+// publish_at is published by the PublishScheduler once that time passes.
+// Publishing returns likely duplicates alongside the idea.
+func (h *Handler) SetStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	var req setStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	now := time.Now()
+	if err := checkPublishAt(req.Status, req.PublishAt, now); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	idea, err := findAuthoredIdea(ctx, db, ideaID, c.GetString("user_id"), isAdmin(c))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	set := bson.M{"status": req.Status, "updated_at": now}
+	update := bson.M{"$set": set}
+	if req.PublishAt != nil {
+		set["publish_at"] = *req.PublishAt
+	} else {
+		update["$unset"] = bson.M{"publish_at": ""}
+	}
+	// Keep the original publish date when an archived idea is republished
+	if req.Status == StatusPublished && idea.PublishedAt == nil {
+		set["published_at"] = now
+	}
+
+	var updated Idea
+	err = db.Collection("ideas").FindOneAndUpdate(ctx,
+		Live(bson.M{"_id": ideaID}),
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_status_failed", "Failed to update idea status", err))
+		return
+	}
+
+	if req.Status == StatusPublished && idea.PublishedAt == nil {
+		metrics.IdeasCreated.Inc()
+	}
+	// Only listed forks are counted, so any status change can change the count
+	if err := recountForkSource(ctx, db, &updated); err != nil {
+		apperror.Abort(c, apperror.Internal("update_forks_count_failed", "Failed to update fork count", err))
+		return
+	}
+	h.indexes.apply(&updated)
+
+	response := gin.H{"data": updated}
+	if req.Status == StatusPublished {
+		response["duplicates"] = duplicatesOf(ctx, db, &updated)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDrafts lists the caller's drafts, most recently edited first
+func (h *Handler) GetDrafts(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	collection := h.client.Database(cfg.MongoDBConfig.Database).Collection("ideas")
+	filter := Live(bson.M{"author_id": c.GetString("user_id"), "status": StatusDraft})
+
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updated_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var drafts []Idea
+	if err := cursor.All(ctx, &drafts); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       drafts,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// PublishScheduler periodically publishes drafts whose publish_at has passed
+type PublishScheduler struct {
+	client   *mongo.Client
+	interval time.Duration
+}
+
+// NewPublishScheduler creates a scheduler that checks for due drafts every interval
+func NewPublishScheduler(client *mongo.Client, interval time.Duration) *PublishScheduler {
+	return &PublishScheduler{
+		client:   client,
+		interval: interval,
+	}
+}
+
+// Run publishes due drafts until ctx is cancelled
+func (s *PublishScheduler) Run(ctx context.Context) {
+	runPeriodically(ctx, s.interval, func(ctx context.Context) {
+		published, err := s.PublishDue(ctx)
+		if err != nil {
+			log.Printf("Failed to publish scheduled ideas: %v", err)
+		} else if published > 0 {
+			log.Printf("Published %d scheduled ideas", published)
+		}
+	})
+}
+
+// PublishDue publishes every draft whose publish_at is not in the future.
+// The update is a single conditional write, so several instances can run the
+// scheduler at once without publishing an idea twice.
+func (s *PublishScheduler) PublishDue(ctx context.Context) (int64, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	db := s.client.Database(cfg.MongoDBConfig.Database)
+	due := Live(bson.M{"status": StatusDraft, "publish_at": bson.M{"$lte": now}})
+
+	// Forks among the due drafts become listed, so their sources' counts change
+	var sources []bson.ObjectID
+	if err := db.Collection("ideas").Distinct(ctx, "forked_from", due).Decode(&sources); err != nil {
+		return 0, err
+	}
+
+	// Drafts published for the first time get their publish date and count
+	// as created; ideas moved back to draft keep their original one. The
+	// date is when the job actually published them rather than publish_at,
+	// which may be long gone after an outage, so the saved search matcher
+	// still sees them as new.
+	first, err := db.Collection("ideas").UpdateMany(ctx,
+		Live(bson.M{"status": StatusDraft, "publish_at": bson.M{"$lte": now}, "published_at": bson.M{"$exists": false}}),
+		bson.M{
+			"$set":   bson.M{"status": StatusPublished, "published_at": now, "updated_at": now},
+			"$unset": bson.M{"publish_at": ""},
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	metrics.IdeasCreated.Add(float64(first.ModifiedCount))
+
+	again, err := db.Collection("ideas").UpdateMany(ctx,
+		due,
+		bson.M{
+			"$set":   bson.M{"status": StatusPublished, "updated_at": now},
+			"$unset": bson.M{"publish_at": ""},
+		},
+	)
+	if err != nil {
+		return first.ModifiedCount, err
+	}
+
+	published := first.ModifiedCount + again.ModifiedCount
+	if err := recountForks(ctx, db, sources...); err != nil {
+		return published, err
+	}
+	return published, nil
+}
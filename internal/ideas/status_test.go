@@ -0,0 +1,49 @@
+package ideas
+
+import (
+	"errors"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"testing"
+	"time"
+)
+
+func TestCheckPublishAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		publishAt *time.Time
+		wantErr   bool
+	}{
+		{"no schedule", StatusPublished, nil, false},
+		{"draft without schedule", StatusDraft, nil, false},
+		{"draft scheduled in the future", StatusDraft, at(time.Minute), false},
+		{"draft scheduled now", StatusDraft, at(0), true},
+		{"draft scheduled in the past", StatusDraft, at(-time.Hour), true},
+		{"published idea scheduled", StatusPublished, at(time.Hour), true},
+		{"archived idea scheduled", StatusArchived, at(time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPublishAt(tt.status, tt.publishAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPublishAt(%s, %v) = %v, want error %v", tt.status, tt.publishAt, err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || appErr.Code != "invalid_publish_at" {
+				t.Fatalf("checkPublishAt() = %v, want an invalid_publish_at validation error", err)
+			}
+			if len(appErr.Fields) != 1 || appErr.Fields[0].Field != "publish_at" {
+				t.Errorf("checkPublishAt() fields = %v, want publish_at", appErr.Fields)
+			}
+		})
+	}
+}
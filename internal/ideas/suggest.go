@@ -0,0 +1,60 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultSuggestLimit is how many ideas and tags Suggest returns by default
+	defaultSuggestLimit = 5
+	// maxSuggestLimit caps the limit parameter of Suggest
+	maxSuggestLimit = 10
+	// maxSuggestQuery caps the length of autocomplete queries
+	maxSuggestQuery = 100
+)
+
+// Suggest autocompletes idea titles and tags from the in-memory index,
+// tolerating typos and ranking by likes
+func (h *Handler) Suggest(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 2*time.Second)
+	defer cancel()
+
+	q := c.Query("q")
+	if q == "" || utf8.RuneCountInString(q) > maxSuggestQuery {
+		apperror.Abort(c, apperror.Validation("invalid_query", "Invalid query", apperror.FieldError{
+			Field:   "q",
+			Message: "must be between 1 and " + strconv.Itoa(maxSuggestQuery) + " characters",
+		}))
+		return
+	}
+
+	limit := defaultSuggestLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSuggestLimit {
+			apperror.Abort(c, apperror.Validation("invalid_limit", "Invalid limit", apperror.FieldError{
+				Field:   "limit",
+				Message: "must be between 1 and " + strconv.Itoa(maxSuggestLimit),
+			}))
+			return
+		}
+		limit = parsed
+	}
+
+	if err := h.indexes.ensureLoaded(ctx); err != nil {
+		apperror.Abort(c, apperror.Internal("load_suggest_index_failed", "Failed to load autocomplete index", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{
+		"ideas": h.indexes.suggest.Ideas(q, limit),
+		"tags":  h.indexes.suggest.Tags(q, limit),
+	}})
+}
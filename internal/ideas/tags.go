@@ -0,0 +1,606 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// taxonomyTTL is how long the cached taxonomy is used before it is
+	// re-read, so tags changed on other instances are picked up
+	taxonomyTTL = time.Minute
+	// maxTagSlugLength matches the length allowed for tags on ideas
+	maxTagSlugLength = 50
+)
+
+var nonTagChars = regexp.MustCompile(`[^\p{L}\p{N}+#.]+`)
+
+// Tag is a canonical tag. Ideas store its slug, and its synonyms are
+// rewritten to the slug on write and match it in filters.
+type Tag struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Slug        string        `bson:"slug" json:"slug"`
+	Name        string        `bson:"name" json:"name"`
+	Description string        `bson:"description" json:"description"`
+	Synonyms    []string      `bson:"synonyms" json:"synonyms"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `bson:"updated_at" json:"updated_at"`
+}
+
+type createTagRequest struct {
+	Name        string   `json:"name" binding:"required,max=50"`
+	Slug        string   `json:"slug" binding:"max=50"`
+	Description string   `json:"description" binding:"max=500"`
+	Synonyms    []string `json:"synonyms" binding:"max=50,dive,required,max=50"`
+}
+
+type updateTagRequest struct {
+	Name        *string   `json:"name" binding:"omitempty,min=1,max=50"`
+	Description *string   `json:"description" binding:"omitempty,max=500"`
+	Synonyms    *[]string `json:"synonyms" binding:"omitempty,max=50,dive,required,max=50"`
+}
+
+type mergeTagRequest struct {
+	Into string `json:"into" binding:"required,max=50"`
+}
+
+// TagSlug turns a tag as typed into its slug. It is lowercased and runs of
+// anything but letters, digits, "+", "#" and "." become a dash, so
+// "Machine Learning" is "machine-learning" while "C++" stays "c++".
+func TagSlug(name string) string {
+	slug := nonTagChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	slug = strings.Trim(slug, "-.")
+	if runes := []rune(slug); len(runes) > maxTagSlugLength {
+		slug = strings.TrimRight(string(runes[:maxTagSlugLength]), "-.")
+	}
+	return slug
+}
+
+// tagSynonyms slugs synonyms, dropping blanks, duplicates and slug itself
+func tagSynonyms(slug string, synonyms []string) []string {
+	result := []string{}
+	seen := map[string]bool{slug: true}
+	for _, synonym := range synonyms {
+		s := TagSlug(synonym)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		result = append(result, s)
+	}
+	return result
+}
+
+// taxonomy caches the tags collection to resolve synonyms without a query
+// per request
+type taxonomy struct {
+	client *mongo.Client
+
+	mu       sync.RWMutex
+	loadedAt time.Time
+	// canonical maps tag slugs and synonyms to the tag slug
+	canonical map[string]string
+	// synonyms maps tag slugs to their synonyms
+	synonyms map[string][]string
+}
+
+func newTaxonomy(client *mongo.Client) *taxonomy {
+	return &taxonomy{
+		client:    client,
+		canonical: map[string]string{},
+		synonyms:  map[string][]string{},
+	}
+}
+
+// load re-reads every tag
+func (t *taxonomy) load(ctx context.Context) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	cursor, err := t.client.Database(cfg.MongoDBConfig.Database).Collection("tags").Find(ctx, bson.M{},
+		options.Find().SetProjection(bson.M{"slug": 1, "synonyms": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	var tags []Tag
+	if err := cursor.All(ctx, &tags); err != nil {
+		return err
+	}
+
+	canonical := make(map[string]string, len(tags))
+	synonyms := make(map[string][]string, len(tags))
+	for _, tag := range tags {
+		canonical[tag.Slug] = tag.Slug
+		for _, synonym := range tag.Synonyms {
+			canonical[synonym] = tag.Slug
+		}
+		if len(tag.Synonyms) > 0 {
+			synonyms[tag.Slug] = tag.Synonyms
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.canonical = canonical
+	t.synonyms = synonyms
+	t.loadedAt = time.Now()
+	return nil
+}
+
+// refresh reloads the taxonomy once it is older than taxonomyTTL. Failures
+// are logged and the cached copy is kept.
+func (t *taxonomy) refresh(ctx context.Context) {
+	t.mu.RLock()
+	fresh := time.Since(t.loadedAt) < taxonomyTTL
+	t.mu.RUnlock()
+	if fresh {
+		return
+	}
+	if err := t.load(ctx); err != nil {
+		log.Printf("Failed to load the tag taxonomy: %v", err)
+	}
+}
+
+// invalidate makes the next lookup reload the taxonomy
+func (t *taxonomy) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadedAt = time.Time{}
+}
+
+// resolve maps tags as typed to canonical slugs in order, dropping blanks
+// and duplicates. Unknown tags are only slugged.
+func (t *taxonomy) resolve(ctx context.Context, tags []string) []string {
+	t.refresh(ctx)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	resolved := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		slug := TagSlug(tag)
+		if canonical, ok := t.canonical[slug]; ok {
+			slug = canonical
+		}
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		resolved = append(resolved, slug)
+	}
+	return resolved
+}
+
+// expand resolves tags and adds their synonyms, so filters also match
+// ideas tagged with a synonym that another instance hadn't resolved yet
+func (t *taxonomy) expand(ctx context.Context, tags []string) []string {
+	resolved := t.resolve(ctx, tags)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	expanded := append([]string{}, resolved...)
+	for _, slug := range resolved {
+		expanded = append(expanded, t.synonyms[slug]...)
+	}
+	return expanded
+}
+
+// normalize resolves the tags written to an idea and adds the ones not in
+// the taxonomy yet, named as first typed
+func (t *taxonomy) normalize(ctx context.Context, db *mongo.Database, tags []string) ([]string, error) {
+	resolved := t.resolve(ctx, tags)
+
+	names := map[string]string{}
+	for _, tag := range tags {
+		slug := TagSlug(tag)
+		if _, ok := names[slug]; !ok {
+			names[slug] = strings.TrimSpace(tag)
+		}
+	}
+
+	for _, slug := range resolved {
+		t.mu.RLock()
+		_, known := t.canonical[slug]
+		t.mu.RUnlock()
+		if known {
+			continue
+		}
+
+		now := time.Now()
+		_, err := db.Collection("tags").UpdateOne(ctx,
+			bson.M{"slug": slug},
+			bson.M{"$setOnInsert": bson.M{
+				"name":        names[slug],
+				"description": "",
+				"synonyms":    []string{},
+				"created_at":  now,
+				"updated_at":  now,
+			}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		// Lost a race with another writer adding the same tag
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		t.mu.Lock()
+		t.canonical[slug] = slug
+		t.mu.Unlock()
+	}
+	return resolved, nil
+}
+
+// normalizeStoredTags adds every tag used by an idea to the taxonomy and
+// rewrites tags to their canonical slugs, for ideas tagged before tags were
+// normalized. It runs once as a migration and returns the number of ideas
+// rewritten.
+func (t *taxonomy) normalizeStoredTags(ctx context.Context, db *mongo.Database) (int64, error) {
+	var used []string
+	if err := db.Collection("ideas").Distinct(ctx, "tags", bson.M{}).Decode(&used); err != nil {
+		return 0, err
+	}
+	if err := t.load(ctx); err != nil {
+		return 0, err
+	}
+
+	renames := map[string][]string{}
+	for _, tag := range used {
+		normalized, err := t.normalize(ctx, db, []string{tag})
+		if err != nil {
+			return 0, err
+		}
+		// Tags without a letter or digit are left for their authors to fix
+		if len(normalized) == 0 || normalized[0] == tag {
+			continue
+		}
+		renames[normalized[0]] = append(renames[normalized[0]], tag)
+	}
+
+	var rewritten int64
+	now := time.Now()
+	for slug, from := range renames {
+		n, err := replaceTags(ctx, db.Collection("ideas"), from, slug, bson.M{"index_changed_at": now})
+		if err != nil {
+			return rewritten, err
+		}
+		rewritten += n
+	}
+	return rewritten, nil
+}
+
+// replaceTags replaces any of from with to in the tags of the documents in
+// coll, keeping their order and dropping duplicates. The fields in set are
+// also set on the rewritten documents.
+func replaceTags(ctx context.Context, coll *mongo.Collection, from []string, to string, set bson.M) (int64, error) {
+	stage := bson.M{"tags": bson.M{"$reduce": bson.M{
+		"input": bson.M{"$map": bson.M{
+			"input": "$tags",
+			"in":    bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$$this", from}}, to, "$$this"}},
+		}},
+		"initialValue": bson.A{},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$in": bson.A{"$$this", "$$value"}},
+			"$$value",
+			bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+		}},
+	}}}
+	for key, value := range set {
+		stage[key] = value
+	}
+
+	result, err := coll.UpdateMany(ctx,
+		bson.M{"tags": bson.M{"$in": from}},
+		mongo.Pipeline{{{Key: "$set", Value: stage}}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// checkSynonyms makes sure none of synonyms is already a tag or a synonym of
+// a tag other than slug
+func checkSynonyms(ctx context.Context, coll *mongo.Collection, slug string, synonyms []string) error {
+	if len(synonyms) == 0 {
+		return nil
+	}
+	n, err := coll.CountDocuments(ctx, bson.M{
+		"slug": bson.M{"$ne": slug},
+		"$or": bson.A{
+			bson.M{"slug": bson.M{"$in": synonyms}},
+			bson.M{"synonyms": bson.M{"$in": synonyms}},
+		},
+	})
+	if err != nil {
+		return apperror.Internal("check_tags_failed", "Failed to check tags", err)
+	}
+	if n > 0 {
+		return errSynonymTaken()
+	}
+	return nil
+}
+
+func errSynonymTaken() *apperror.Error {
+	return apperror.Conflict("synonym_taken", "A synonym is already a tag or another tag's synonym; merge the tags instead")
+}
+
+func errNotModerator() *apperror.Error {
+	return apperror.Forbidden("not_moderator", "Only moderators can manage tags")
+}
+
+// findTag loads the tag with slug
+func findTag(ctx context.Context, db *mongo.Database, slug string) (*Tag, error) {
+	var tag Tag
+	err := db.Collection("tags").FindOne(ctx, bson.M{"slug": slug}).Decode(&tag)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("tag_not_found", "Tag not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_tag_failed", "Failed to fetch tag", err)
+	}
+	return &tag, nil
+}
+
+// CreateTag adds a canonical tag with its synonyms. Only moderators (admins)
+// can manage tags.
+func (h *Handler) CreateTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	if !isAdmin(c) {
+		apperror.Abort(c, errNotModerator())
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req createTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		slug = req.Name
+	}
+	slug = TagSlug(slug)
+	if slug == "" {
+		apperror.Abort(c, apperror.Validation("invalid_tag_slug", "Invalid tag slug", apperror.FieldError{
+			Field:   "slug",
+			Message: "must contain a letter or digit",
+		}))
+		return
+	}
+
+	now := time.Now()
+	tag := Tag{
+		ID:          bson.NewObjectID(),
+		Slug:        slug,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Synonyms:    tagSynonyms(slug, req.Synonyms),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	tags := db.Collection("tags")
+
+	n, err := tags.CountDocuments(ctx, bson.M{"synonyms": slug})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("check_tags_failed", "Failed to check tags", err))
+		return
+	}
+	if n > 0 {
+		apperror.Abort(c, apperror.Conflict("tag_is_synonym", "This tag is already a synonym of another tag"))
+		return
+	}
+	if err := checkSynonyms(ctx, tags, slug, tag.Synonyms); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	_, err = tags.InsertOne(ctx, tag)
+	if mongo.IsDuplicateKeyError(err) {
+		apperror.Abort(c, apperror.Conflict("tag_exists", "A tag with this slug already exists"))
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("create_tag_failed", "Failed to create tag", err))
+		return
+	}
+	h.taxonomy.invalidate()
+	h.tagStats.invalidate()
+
+	c.JSON(http.StatusCreated, gin.H{"data": tag})
+}
+
+// UpdateTag changes a tag's name, description or synonyms. Only moderators
+// (admins) can manage tags.
+func (h *Handler) UpdateTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	if !isAdmin(c) {
+		apperror.Abort(c, errNotModerator())
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req updateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	tag, err := findTag(ctx, db, c.Param("slug"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if req.Name != nil {
+		set["name"] = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		set["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Synonyms != nil {
+		synonyms := tagSynonyms(tag.Slug, *req.Synonyms)
+		if err := checkSynonyms(ctx, db.Collection("tags"), tag.Slug, synonyms); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		set["synonyms"] = synonyms
+	}
+
+	var updated Tag
+	err = db.Collection("tags").FindOneAndUpdate(ctx,
+		bson.M{"_id": tag.ID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if mongo.IsDuplicateKeyError(err) {
+		apperror.Abort(c, errSynonymTaken())
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_tag_failed", "Failed to update tag", err))
+		return
+	}
+	h.taxonomy.invalidate()
+	h.tagStats.invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// MergeTag folds a tag into the tag given in the body. The merged tag and
+// its synonyms become synonyms of the surviving tag, and ideas and saved
+// searches using them are rewritten. Its followers follow the surviving tag
+// instead. Only moderators (admins) can merge.
+func (h *Handler) MergeTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 60*time.Second)
+	defer cancel()
+
+	if !isAdmin(c) {
+		apperror.Abort(c, errNotModerator())
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req mergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	source, err := findTag(ctx, db, c.Param("slug"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+	if TagSlug(req.Into) == source.Slug {
+		apperror.Abort(c, apperror.Validation("merge_into_self", "A tag can't be merged into itself", apperror.FieldError{
+			Field:   "into",
+			Message: "must be a different tag",
+		}))
+		return
+	}
+	target, err := findTag(ctx, db, TagSlug(req.Into))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	merged := append([]string{source.Slug}, source.Synonyms...)
+	var survivor Tag
+	var rewritten int64
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		if _, err := db.Collection("tags").DeleteOne(sessCtx, bson.M{"_id": source.ID}); err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		err := db.Collection("tags").FindOneAndUpdate(sessCtx,
+			bson.M{"_id": target.ID},
+			bson.M{
+				"$addToSet": bson.M{"synonyms": bson.M{"$each": merged}},
+				"$set":      bson.M{"updated_at": now},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&survivor)
+		if err != nil {
+			return nil, err
+		}
+
+		// A moderator merge isn't an edit by the author, so updated_at is
+		// left alone and index_changed_at tells every instance's in-memory
+		// indexes to pick up the new tags on their next sync
+		rewritten, err = replaceTags(sessCtx, db.Collection("ideas"), merged, target.Slug, bson.M{"index_changed_at": now})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := replaceTags(sessCtx, db.Collection("saved_searches"), merged, target.Slug, nil); err != nil {
+			return nil, err
+		}
+		return nil, mergeTagFollows(sessCtx, db, merged, target.Slug)
+	})
+	if err != nil {
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) {
+			err = apperror.Internal("merge_tags_failed", "Failed to merge tags", err)
+		}
+		apperror.Abort(c, err)
+		return
+	}
+	h.taxonomy.invalidate()
+	h.tagStats.invalidate()
+	if err := h.indexes.Sync(ctx); err != nil {
+		log.Printf("Failed to sync in-memory indexes after merging tag %s: %v", source.Slug, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": survivor, "ideas_updated": rewritten})
+}
@@ -0,0 +1,85 @@
+package ideas
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTagSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"go", "go"},
+		{"Machine Learning", "machine-learning"},
+		{"  React  ", "react"},
+		{"C++", "c++"},
+		{"C#", "c#"},
+		{"Node.js", "node.js"},
+		{".NET", "net"},
+		{"web / mobile", "web-mobile"},
+		{"--hyphens--", "hyphens"},
+		{"Café Crème", "café-crème"},
+		{"日本語", "日本語"},
+		{"!!!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TagSlug(tt.name); got != tt.want {
+			t.Errorf("TagSlug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTagSlugCapsLength(t *testing.T) {
+	got := TagSlug(strings.Repeat("é", maxTagSlugLength+10))
+	if n := len([]rune(got)); n != maxTagSlugLength {
+		t.Errorf("TagSlug() kept %d runes, want %d", n, maxTagSlugLength)
+	}
+
+	// A cut right after a separator doesn't leave a trailing dash
+	got = TagSlug(strings.Repeat("a", maxTagSlugLength-1) + " tail")
+	if strings.HasSuffix(got, "-") {
+		t.Errorf("TagSlug() = %q, want no trailing dash", got)
+	}
+}
+
+func TestTagSynonyms(t *testing.T) {
+	got := tagSynonyms("go", []string{"Golang", "golang", "Go", " ", "Go Lang"})
+	if want := []string{"golang", "go-lang"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tagSynonyms() = %v, want %v", got, want)
+	}
+	if got := tagSynonyms("go", nil); got == nil || len(got) != 0 {
+		t.Errorf("tagSynonyms(nil) = %#v, want an empty slice", got)
+	}
+}
+
+func TestTaxonomyResolveAndExpand(t *testing.T) {
+	tx := &taxonomy{
+		// Loaded just now, so lookups don't reload it from the database
+		loadedAt: time.Now(),
+		canonical: map[string]string{
+			"go":     "go",
+			"golang": "go",
+			"js":     "javascript",
+		},
+		synonyms: map[string][]string{
+			"go":         {"golang"},
+			"javascript": {"js"},
+		},
+	}
+	ctx := context.Background()
+
+	got := tx.resolve(ctx, []string{"Golang", "JS", "go", "Rust Lang", "", "rust lang"})
+	if want := []string{"go", "javascript", "rust-lang"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resolve() = %v, want %v", got, want)
+	}
+
+	got = tx.expand(ctx, []string{"golang", "rust"})
+	if want := []string{"go", "rust", "golang"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expand() = %v, want %v", got, want)
+	}
+}
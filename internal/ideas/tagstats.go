@@ -0,0 +1,391 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// tagStatsTTL is how long tag statistics are served from memory
+	tagStatsTTL = 5 * time.Minute
+	// relatedTagsLimit is how many co-occurring tags a tag's detail shows
+	relatedTagsLimit = 10
+	// topIdeasLimit is how many of its most liked ideas a tag's detail shows
+	topIdeasLimit = 5
+	// tagStatsTimeout bounds a computation shared by every waiting request
+	tagStatsTimeout = 10 * time.Second
+)
+
+// TagStats is how much listed ideas use a tag
+type TagStats struct {
+	IdeasCount int `bson:"ideas_count" json:"ideas_count"`
+	LikesCount int `bson:"likes_count" json:"likes_count"`
+	// NewIdeas7d and NewIdeas30d count ideas published in the last 7 and 30 days
+	NewIdeas7d  int `bson:"new_ideas_7d" json:"new_ideas_7d"`
+	NewIdeas30d int `bson:"new_ideas_30d" json:"new_ideas_30d"`
+	// PrevIdeas7d and PrevIdeas30d count ideas published in the 7 and 30
+	// days before those
+	PrevIdeas7d  int `bson:"prev_ideas_7d" json:"-"`
+	PrevIdeas30d int `bson:"prev_ideas_30d" json:"-"`
+	// Growth7d and Growth30d are the relative change in new ideas against
+	// the previous period, 0.5 being 50% more. They are null when the
+	// previous period had none.
+	Growth7d  *float64 `bson:"-" json:"growth_7d"`
+	Growth30d *float64 `bson:"-" json:"growth_30d"`
+}
+
+// growth is the relative change from previous to current, or nil without a
+// previous value to compare against
+func growth(current, previous int) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	g := float64(current-previous) / float64(previous)
+	return &g
+}
+
+// TagWithStats is a tag in the tag listing
+type TagWithStats struct {
+	Tag   `bson:",inline"`
+	Stats TagStats `json:"stats"`
+}
+
+// RelatedTag is a tag often used together with another one
+type RelatedTag struct {
+	Slug  string `bson:"_id" json:"slug"`
+	Name  string `bson:"-" json:"name"`
+	Count int    `bson:"count" json:"count"`
+}
+
+// TagDetail is a tag with its statistics, related tags and most liked ideas
+type TagDetail struct {
+	TagWithStats `bson:",inline"`
+	RelatedTags  []RelatedTag `json:"related_tags"`
+	TopIdeas     []Idea       `json:"top_ideas"`
+}
+
+type cachedTagDetail struct {
+	detail     *TagDetail
+	computedAt time.Time
+}
+
+// tagStatsCache holds the tag statistics computed from ideas, shared by the
+// tag endpoints until they expire. Statistics are computed outside the lock,
+// once for all the requests waiting on them.
+type tagStatsCache struct {
+	group singleflight.Group
+
+	mu         sync.Mutex
+	computedAt time.Time
+	tags       []TagWithStats
+	details    map[string]cachedTagDetail
+	// generation changes on invalidate, so statistics computed from data
+	// that changed meanwhile aren't cached
+	generation uint64
+}
+
+func newTagStatsCache() *tagStatsCache {
+	return &tagStatsCache{details: map[string]cachedTagDetail{}}
+}
+
+// all returns every tag with its statistics, computing them if the cached
+// ones expired
+func (t *tagStatsCache) all(ctx context.Context, db *mongo.Database) ([]TagWithStats, error) {
+	t.mu.Lock()
+	if time.Since(t.computedAt) < tagStatsTTL {
+		tags := t.tags
+		t.mu.Unlock()
+		return tags, nil
+	}
+	generation := t.generation
+	t.mu.Unlock()
+
+	v, err, _ := t.group.Do("all", func() (interface{}, error) {
+		// The result is shared, so one caller giving up mustn't cancel it
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tagStatsTimeout)
+		defer cancel()
+
+		tags, err := computeTagStats(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.generation == generation {
+			t.tags = tags
+			t.computedAt = time.Now()
+		}
+		return tags, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]TagWithStats), nil
+}
+
+// detail returns the detail of tag, computing it if the cached one expired
+func (t *tagStatsCache) detail(ctx context.Context, db *mongo.Database, tag *Tag) (*TagDetail, error) {
+	tags, err := t.all(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if cached, ok := t.details[tag.Slug]; ok && time.Since(cached.computedAt) < tagStatsTTL {
+		t.mu.Unlock()
+		return cached.detail, nil
+	}
+	generation := t.generation
+	t.mu.Unlock()
+
+	v, err, _ := t.group.Do("detail:"+tag.Slug, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tagStatsTimeout)
+		defer cancel()
+
+		detail := &TagDetail{TagWithStats: TagWithStats{Tag: *tag}}
+		for _, ts := range tags {
+			if ts.Slug == tag.Slug {
+				detail.Stats = ts.Stats
+				break
+			}
+		}
+		var err error
+		if detail.RelatedTags, err = relatedTags(ctx, db, tag.Slug, tags); err != nil {
+			return nil, err
+		}
+		if detail.TopIdeas, err = topTagIdeas(ctx, db, tag.Slug); err != nil {
+			return nil, err
+		}
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if t.generation == generation {
+			// Drop expired details so tags viewed once don't pile up
+			for slug, cached := range t.details {
+				if time.Since(cached.computedAt) >= tagStatsTTL {
+					delete(t.details, slug)
+				}
+			}
+			t.details[tag.Slug] = cachedTagDetail{detail: detail, computedAt: time.Now()}
+		}
+		return detail, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*TagDetail), nil
+}
+
+// invalidate drops the cached statistics after tags are changed
+func (t *tagStatsCache) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.generation++
+	t.computedAt = time.Time{}
+	t.details = map[string]cachedTagDetail{}
+}
+
+// computeTagStats aggregates the usage of every tag over listed ideas. Tags
+// no listed idea uses are included with zero counts.
+func computeTagStats(ctx context.Context, db *mongo.Database) ([]TagWithStats, error) {
+	now := time.Now()
+	published := bson.M{"$ifNull": bson.A{"$published_at", "$created_at"}}
+	// publishedBetween counts ideas published from fromDays to toDays ago
+	publishedBetween := func(fromDays, toDays int) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"$gte": bson.A{published, now.AddDate(0, 0, -fromDays)}},
+				bson.M{"$lt": bson.A{published, now.AddDate(0, 0, -toDays)}},
+			}}, 1, 0,
+		}}}
+	}
+
+	cursor, err := db.Collection("ideas").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: Live(bson.M{"status": listedStatuses()})}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$tags",
+			"ideas_count":    bson.M{"$sum": 1},
+			"likes_count":    bson.M{"$sum": "$likes_count"},
+			"new_ideas_7d":   publishedBetween(7, 0),
+			"new_ideas_30d":  publishedBetween(30, 0),
+			"prev_ideas_7d":  publishedBetween(14, 7),
+			"prev_ideas_30d": publishedBetween(60, 30),
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var usage []struct {
+		Slug     string `bson:"_id"`
+		TagStats `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, err
+	}
+	stats := make(map[string]TagStats, len(usage))
+	for _, u := range usage {
+		u.Growth7d = growth(u.NewIdeas7d, u.PrevIdeas7d)
+		u.Growth30d = growth(u.NewIdeas30d, u.PrevIdeas30d)
+		stats[u.Slug] = u.TagStats
+	}
+
+	cursor, err = db.Collection("tags").Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "slug", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var tags []Tag
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+
+	result := make([]TagWithStats, len(tags))
+	for i, tag := range tags {
+		result[i] = TagWithStats{Tag: tag, Stats: stats[tag.Slug]}
+	}
+	return result, nil
+}
+
+// relatedTags returns the tags most often used together with slug on listed
+// ideas, named from tags
+func relatedTags(ctx context.Context, db *mongo.Database, slug string, tags []TagWithStats) ([]RelatedTag, error) {
+	cursor, err := db.Collection("ideas").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: Live(bson.M{"status": listedStatuses(), "tags": slug})}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$match", Value: bson.M{"tags": bson.M{"$ne": slug}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$limit", Value: relatedTagsLimit}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	related := []RelatedTag{}
+	if err := cursor.All(ctx, &related); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		names[tag.Slug] = tag.Name
+	}
+	for i := range related {
+		related[i].Name = names[related[i].Slug]
+		if related[i].Name == "" {
+			related[i].Name = related[i].Slug
+		}
+	}
+	return related, nil
+}
+
+// topTagIdeas returns the most liked listed ideas tagged with slug
+func topTagIdeas(ctx context.Context, db *mongo.Database, slug string) ([]Idea, error) {
+	cursor, err := db.Collection("ideas").Find(ctx,
+		Live(bson.M{"status": listedStatuses(), "tags": slug}),
+		options.Find().
+			SetSort(bson.D{{Key: "likes_count", Value: -1}, {Key: "created_at", Value: -1}}).
+			SetLimit(topIdeasLimit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	ideas := []Idea{}
+	if err := cursor.All(ctx, &ideas); err != nil {
+		return nil, err
+	}
+	refs := make([]*Idea, len(ideas))
+	for i := range ideas {
+		NormalizeStatus(&ideas[i])
+		refs[i] = &ideas[i]
+	}
+	EmbedAuthors(ctx, db, refs...)
+	return ideas, nil
+}
+
+// GetTags lists tags with their usage statistics, most used first or by name
+// with sort=name. Statistics are cached for a few minutes.
+func (h *Handler) GetTags(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	all, err := h.tagStats.all(ctx, db)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_tags_failed", "Failed to fetch tags", err))
+		return
+	}
+
+	// The cached slice is shared, so sort a copy
+	tags := append([]TagWithStats{}, all...)
+	if c.Query("sort") == "name" {
+		sort.SliceStable(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	} else {
+		sort.SliceStable(tags, func(i, j int) bool {
+			if tags[i].Stats.IdeasCount != tags[j].Stats.IdeasCount {
+				return tags[i].Stats.IdeasCount > tags[j].Stats.IdeasCount
+			}
+			return tags[i].Stats.LikesCount > tags[j].Stats.LikesCount
+		})
+	}
+
+	page, pageSize := pagination.Parse(c)
+	start := min((page-1)*pageSize, len(tags))
+	end := min(start+pageSize, len(tags))
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       tags[start:end],
+		"pagination": pagination.Meta(page, pageSize, int64(len(tags))),
+	})
+}
+
+// GetTag returns a tag with its statistics, related tags and most liked
+// ideas. A synonym returns the tag it belongs to.
+func (h *Handler) GetTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	slug := c.Param("slug")
+	if resolved := h.taxonomy.resolve(ctx, []string{slug}); len(resolved) > 0 {
+		slug = resolved[0]
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	tag, err := findTag(ctx, db, slug)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	detail, err := h.tagStats.detail(ctx, db, tag)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_tag_failed", "Failed to fetch tag", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": detail})
+}
@@ -0,0 +1,47 @@
+package ideas
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGrowth(t *testing.T) {
+	tests := []struct {
+		current, previous int
+		want              float64
+	}{
+		{15, 10, 0.5},
+		{10, 10, 0},
+		{5, 10, -0.5},
+		{0, 4, -1},
+		{30, 10, 2},
+	}
+	for _, tt := range tests {
+		got := growth(tt.current, tt.previous)
+		if got == nil || *got != tt.want {
+			t.Errorf("growth(%d, %d) = %v, want %v", tt.current, tt.previous, got, tt.want)
+		}
+	}
+
+	if got := growth(3, 0); got != nil {
+		t.Errorf("growth(3, 0) = %v, want nil without a previous period", *got)
+	}
+}
+
+func TestTagStatsJSON(t *testing.T) {
+	stats := TagStats{NewIdeas7d: 3, PrevIdeas7d: 2, Growth7d: growth(3, 2), Growth30d: growth(3, 0)}
+	b, err := json.Marshal(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, want := range []string{`"growth_7d":0.5`, `"growth_30d":null`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("json = %s, want %s", s, want)
+		}
+	}
+	if strings.Contains(s, "prev_ideas") {
+		t.Errorf("json = %s, want the previous periods left out", s)
+	}
+}
@@ -0,0 +1,413 @@
+package ideas
+
+import (
+	"context"
+	"errors"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// defaultRetentionDays is used when trash.retentionDays is not configured
+const defaultRetentionDays = 30
+
+// purgeBatchSize caps how many ideas one purge pass removes
+const purgeBatchSize = 500
+
+// PurgeHook removes data another package keeps about a purged idea
+type PurgeHook func(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID) error
+
+// restoreWindow is how long deleted items can be restored before they are purged
+func restoreWindow(cfg *config.Config) time.Duration {
+	days := cfg.TrashConfig.RetentionDays
+	if days <= 0 {
+		days = defaultRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func errInvalidCommentID() *apperror.Error {
+	return apperror.Validation("invalid_comment_id", "Invalid comment ID", apperror.FieldError{
+		Field:   "commentId",
+		Message: "must be a 24 character hex ObjectID",
+	})
+}
+
+func errRestoreWindowExpired() *apperror.Error {
+	return apperror.Conflict("restore_window_expired", "The restore window for this item has passed")
+}
+
+func errIdeaMerged() *apperror.Error {
+	return apperror.Conflict("idea_merged", "Idea was merged into another idea and can't be restored")
+}
+
+// DeleteIdea moves an idea the caller authored to the trash
+func (h *Handler) DeleteIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	idea, err := findAuthoredIdea(ctx, db, ideaID, userID, isAdmin(c))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	result, err := db.Collection("ideas").UpdateOne(ctx,
+		Live(bson.M{"_id": ideaID}),
+		bson.M{"$set": bson.M{"deleted_at": time.Now(), "deleted_by": userID}},
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("delete_idea_failed", "Failed to delete idea", err))
+		return
+	}
+	if result.MatchedCount == 0 {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea not found"))
+		return
+	}
+	if err := recountForkSource(ctx, db, idea); err != nil {
+		apperror.Abort(c, apperror.Internal("update_forks_count_failed", "Failed to update fork count", err))
+		return
+	}
+	h.indexes.Refresh(ctx, db, ideaID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Idea moved to trash"})
+}
+
+// RestoreIdea takes an idea out of the trash while the restore window is open
+func (h *Handler) RestoreIdea(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	collection := db.Collection("ideas")
+
+	var idea Idea
+	err = collection.FindOne(ctx, bson.M{"_id": ideaID, "deleted_at": bson.M{"$exists": true}}).Decode(&idea)
+	if err == mongo.ErrNoDocuments {
+		apperror.Abort(c, apperror.NotFound("idea_not_found", "Idea is not in the trash"))
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_idea_failed", "Failed to fetch idea", err))
+		return
+	}
+	if idea.AuthorID != c.GetString("user_id") && !isAdmin(c) {
+		apperror.Abort(c, apperror.Forbidden("not_idea_author", "Only the idea author can restore this idea"))
+		return
+	}
+	// Its likes, comments and bookmarks were moved to the surviving idea
+	if idea.MergedInto != nil {
+		apperror.Abort(c, errIdeaMerged())
+		return
+	}
+
+	cutoff := time.Now().Add(-restoreWindow(cfg))
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": ideaID, "deleted_at": bson.M{"$gt": cutoff}},
+		bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}},
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("restore_idea_failed", "Failed to restore idea", err))
+		return
+	}
+	if result.MatchedCount == 0 {
+		apperror.Abort(c, errRestoreWindowExpired())
+		return
+	}
+	if err := recountForkSource(ctx, db, &idea); err != nil {
+		apperror.Abort(c, apperror.Internal("update_forks_count_failed", "Failed to update fork count", err))
+		return
+	}
+	h.indexes.Refresh(ctx, db, ideaID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Idea restored successfully"})
+}
+
+// DeleteComment moves a comment the caller wrote to the trash
+func (h *Handler) DeleteComment(c *gin.Context) {
+	h.setCommentDeleted(c, true)
+}
+
+// RestoreComment takes a comment out of the trash while the restore window is open
+func (h *Handler) RestoreComment(c *gin.Context) {
+	h.setCommentDeleted(c, false)
+}
+
+func (h *Handler) setCommentDeleted(c *gin.Context, deleted bool) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	ideaID, err := bson.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, errInvalidIdeaID())
+		return
+	}
+	commentID, err := bson.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		apperror.Abort(c, errInvalidCommentID())
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	comments := db.Collection("comments")
+
+	var comment Comment
+	err = comments.FindOne(ctx, bson.M{
+		"_id":        commentID,
+		"idea_id":    ideaID,
+		"deleted_at": bson.M{"$exists": !deleted},
+	}).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		apperror.Abort(c, apperror.NotFound("comment_not_found", "Comment not found"))
+		return
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_comment_failed", "Failed to fetch comment", err))
+		return
+	}
+	if comment.UserID != userID && !isAdmin(c) {
+		apperror.Abort(c, apperror.Forbidden("not_comment_author", "Only the comment author can change this comment"))
+		return
+	}
+
+	// The conditional update keeps comments_count right when requests race
+	filter := Live(bson.M{"_id": commentID})
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now(), "deleted_by": userID}}
+	inc := -1
+	if !deleted {
+		filter = bson.M{"_id": commentID, "deleted_at": bson.M{"$gt": time.Now().Add(-restoreWindow(cfg))}}
+		update = bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}}
+		inc = 1
+	}
+
+	result, err := comments.UpdateOne(ctx, filter, update)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_comment_failed", "Failed to update comment", err))
+		return
+	}
+	if result.ModifiedCount == 0 {
+		if deleted {
+			apperror.Abort(c, apperror.NotFound("comment_not_found", "Comment not found"))
+		} else {
+			apperror.Abort(c, errRestoreWindowExpired())
+		}
+		return
+	}
+
+	_, err = db.Collection("ideas").UpdateOne(ctx,
+		bson.M{"_id": ideaID},
+		bson.M{"$inc": bson.M{"comments_count": inc}},
+	)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_comment_count_failed", "Failed to update comment count", err))
+		return
+	}
+
+	message := "Comment moved to trash"
+	if !deleted {
+		message = "Comment restored successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// GetTrashedIdeas lists the caller's deleted ideas that can still be restored.
+// Admins can pass all=true to see everyone's.
+func (h *Handler) GetTrashedIdeas(c *gin.Context) {
+	var ideas []Idea
+	h.listTrash(c, "ideas", "author_id", &ideas)
+}
+
+// GetTrashedComments lists the caller's deleted comments that can still be
+// restored. Admins can pass all=true to see everyone's.
+func (h *Handler) GetTrashedComments(c *gin.Context) {
+	var comments []Comment
+	h.listTrash(c, "comments", "user_id", &comments)
+}
+
+// listTrash writes a page of restorable documents from collName, newest
+// deletion first, decoded into results
+func (h *Handler) listTrash(c *gin.Context, collName, ownerField string, results interface{}) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	window := restoreWindow(cfg)
+	filter := bson.M{"deleted_at": bson.M{"$gt": time.Now().Add(-window)}}
+	if !(isAdmin(c) && c.Query("all") == "true") {
+		filter[ownerField] = c.GetString("user_id")
+	}
+
+	collection := h.client.Database(cfg.MongoDBConfig.Database).Collection(collName)
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_trash_failed", "Failed to count trash", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_trash_failed", "Failed to fetch trash", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, results); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_trash_failed", "Failed to decode trash", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":                results,
+		"restore_window_days": int(window.Hours() / 24),
+		"pagination":          pagination.Meta(page, pageSize, total),
+	})
+}
+
+// Purger permanently removes ideas and comments whose restore window has passed
+type Purger struct {
+	client   *mongo.Client
+	interval time.Duration
+	hooks    []PurgeHook
+}
+
+// NewPurger creates a purger that runs every interval. hooks are called for
+// each purged idea before its own data is removed.
+func NewPurger(client *mongo.Client, interval time.Duration, hooks ...PurgeHook) *Purger {
+	return &Purger{
+		client:   client,
+		interval: interval,
+		hooks:    hooks,
+	}
+}
+
+// Run purges expired trash until ctx is cancelled
+func (p *Purger) Run(ctx context.Context) {
+	runPeriodically(ctx, p.interval, func(ctx context.Context) {
+		ideas, comments, err := p.Purge(ctx)
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if ideas > 0 || comments > 0 {
+			log.Printf("Purged %d ideas and %d comments from the trash", ideas, comments)
+		}
+	})
+}
+
+// Purge removes expired ideas, with their likes, bookmarks, comments, builds,
+// details and revisions, and expired comments. It returns how many of each were removed.
+func (p *Purger) Purge(ctx context.Context) (int64, int64, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	db := p.client.Database(cfg.MongoDBConfig.Database)
+	expired := bson.M{"deleted_at": bson.M{"$lte": time.Now().Add(-restoreWindow(cfg))}}
+
+	cursor, err := db.Collection("ideas").Find(ctx, expired,
+		options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(purgeBatchSize),
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+	var ideas []struct {
+		ID bson.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &ideas); err != nil {
+		return 0, 0, err
+	}
+
+	var purgedIdeas int64
+	var errs []error
+	for _, idea := range ideas {
+		if err := p.purgeIdea(ctx, db, idea.ID); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		purgedIdeas++
+	}
+
+	result, err := db.Collection("comments").DeleteMany(ctx, expired)
+	if err != nil {
+		errs = append(errs, err)
+		return purgedIdeas, 0, errors.Join(errs...)
+	}
+	return purgedIdeas, result.DeletedCount, errors.Join(errs...)
+}
+
+// purgeIdea removes everything that references an idea, then the idea itself,
+// so an interrupted purge is picked up again on the next run
+func (p *Purger) purgeIdea(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID) error {
+	for _, hook := range p.hooks {
+		if err := hook(ctx, db, ideaID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.Collection("likes").DeleteMany(ctx, bson.M{"idea_id": ideaID}); err != nil {
+		return err
+	}
+	for _, collName := range []string{"bookmarks", "comments", "dismissals", "idea_builds", "notifications", "idea_details", "idea_revisions"} {
+		if _, err := db.Collection(collName).DeleteMany(ctx, bson.M{"idea_id": ideaID}); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Collection("ideas").DeleteOne(ctx, bson.M{"_id": ideaID, "deleted_at": bson.M{"$exists": true}})
+	return err
+}
@@ -0,0 +1,554 @@
+package ideas
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/user"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	// profileRefreshInterval is how often a signed-in user's profile is
+	// updated from the Clerk user loaded to authenticate them
+	profileRefreshInterval = time.Hour
+	// profileMaxAge is how old a profile can get before the ProfileSyncer
+	// re-reads it from Clerk
+	profileMaxAge = 24 * time.Hour
+	// clerkBatch is how many users are fetched from Clerk per request
+	clerkBatch = 100
+	// profileSyncTimeout bounds a run of the ProfileSyncer
+	profileSyncTimeout = 5 * time.Minute
+)
+
+// Profile is the public profile of a user. The display name and avatar are
+// kept in sync from Clerk; the bio and links are edited here.
+type Profile struct {
+	ID          string        `bson:"_id" json:"id"`
+	DisplayName string        `bson:"display_name" json:"display_name"`
+	AvatarURL   string        `bson:"avatar_url" json:"avatar_url"`
+	Bio         string        `bson:"bio" json:"bio"`
+	Links       []ProfileLink `bson:"links" json:"links"`
+	CreatedAt   time.Time     `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time     `bson:"updated_at" json:"updated_at"`
+	SyncedAt    time.Time     `bson:"synced_at" json:"-"`
+}
+
+// ProfileLink is a link shown on a profile
+type ProfileLink struct {
+	Label string `bson:"label" json:"label" binding:"required,max=50"`
+	URL   string `bson:"url" json:"url" binding:"required,url,max=300"`
+}
+
+// UserProfile is a profile with the user's activity counts
+type UserProfile struct {
+	Profile       `bson:",inline"`
+	IdeasCount    int64 `json:"ideas_count"`
+	LikesReceived int64 `json:"likes_received"`
+	*FollowCounts
+}
+
+// AuthorSummary is the part of the author's profile embedded in ideas
+type AuthorSummary struct {
+	ID          string `bson:"_id" json:"id"`
+	DisplayName string `bson:"display_name" json:"display_name"`
+	AvatarURL   string `bson:"avatar_url" json:"avatar_url"`
+}
+
+type updateProfileRequest struct {
+	Bio   *string        `json:"bio" binding:"omitempty,max=500"`
+	Links *[]ProfileLink `json:"links" binding:"omitempty,max=5,dive"`
+}
+
+// displayName is the full name of a Clerk user, or their username without one
+func displayName(usr *clerk.User) string {
+	var parts []string
+	for _, part := range []*string{usr.FirstName, usr.LastName} {
+		if part != nil && strings.TrimSpace(*part) != "" {
+			parts = append(parts, strings.TrimSpace(*part))
+		}
+	}
+	if len(parts) == 0 && usr.Username != nil {
+		return *usr.Username
+	}
+	return strings.Join(parts, " ")
+}
+
+// upsertProfile copies the Clerk fields of usr into their profile, creating
+// it with an empty bio and links the first time
+func upsertProfile(ctx context.Context, db *mongo.Database, usr *clerk.User) error {
+	avatarURL := ""
+	if usr.ImageURL != nil {
+		avatarURL = *usr.ImageURL
+	}
+
+	now := time.Now()
+	_, err := db.Collection("users").UpdateOne(ctx,
+		bson.M{"_id": usr.ID},
+		bson.M{
+			"$set": bson.M{
+				"display_name": displayName(usr),
+				"avatar_url":   avatarURL,
+				"synced_at":    now,
+				"updated_at":   now,
+			},
+			"$setOnInsert": bson.M{
+				"bio":        "",
+				"links":      []ProfileLink{},
+				"created_at": now,
+			},
+		},
+		options.UpdateOne().SetUpsert(true),
+	)
+	return err
+}
+
+// profileSyncs remembers when each signed-in user's profile was last synced
+// by this instance, so it isn't written on every request
+type profileSyncs struct {
+	mu     sync.Mutex
+	synced map[string]time.Time
+}
+
+// due reports whether userID's profile should be synced, and if so records
+// it as synced now
+func (p *profileSyncs) due(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(p.synced[userID]) < profileRefreshInterval {
+		return false
+	}
+	// Forget users who stopped signing in
+	for id, at := range p.synced {
+		if now.Sub(at) >= profileRefreshInterval {
+			delete(p.synced, id)
+		}
+	}
+	p.synced[userID] = now
+	return true
+}
+
+// SyncProfile updates the profile of a user who just authenticated from their
+// Clerk user, at most once per profileRefreshInterval. Failures are logged.
+func (h *Handler) SyncProfile(ctx context.Context, usr *clerk.User) {
+	if !h.profileSyncs.due(usr.ID) {
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("Failed to sync profile of %s: %v", usr.ID, err)
+		return
+	}
+	if err := upsertProfile(ctx, h.client.Database(cfg.MongoDBConfig.Database), usr); err != nil {
+		log.Printf("Failed to sync profile of %s: %v", usr.ID, err)
+	}
+}
+
+// EmbedAuthors sets the author summary of ideas from their authors'
+// profiles. Authors without a profile get a summary with only their ID.
+// Failures are logged and leave the summaries out.
+func EmbedAuthors(ctx context.Context, db *mongo.Database, ideas ...*Idea) {
+	if len(ideas) == 0 {
+		return
+	}
+
+	ids := make([]string, 0, len(ideas))
+	seen := map[string]bool{}
+	for _, idea := range ideas {
+		if !seen[idea.AuthorID] {
+			seen[idea.AuthorID] = true
+			ids = append(ids, idea.AuthorID)
+		}
+	}
+
+	cursor, err := db.Collection("users").Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"display_name": 1, "avatar_url": 1}),
+	)
+	if err != nil {
+		log.Printf("Failed to fetch authors: %v", err)
+		return
+	}
+	var authors []AuthorSummary
+	if err := cursor.All(ctx, &authors); err != nil {
+		log.Printf("Failed to decode authors: %v", err)
+		return
+	}
+
+	byID := make(map[string]*AuthorSummary, len(authors))
+	for i := range authors {
+		byID[authors[i].ID] = &authors[i]
+	}
+	for _, idea := range ideas {
+		author, ok := byID[idea.AuthorID]
+		if !ok {
+			author = &AuthorSummary{ID: idea.AuthorID}
+		}
+		idea.Author = author
+	}
+}
+
+// findProfile loads a user's profile. Users are only known once they signed
+// in or authored an idea; authors whose profile the ProfileSyncer hasn't
+// fetched from Clerk yet get an empty one with only their ID.
+func findProfile(ctx context.Context, db *mongo.Database, userID string) (*Profile, error) {
+	var profile Profile
+	err := db.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&profile)
+	if err == nil {
+		return &profile, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, apperror.Internal("fetch_user_failed", "Failed to fetch user", err)
+	}
+
+	err = db.Collection("ideas").FindOne(ctx,
+		Live(bson.M{"author_id": userID}),
+		options.FindOne().SetProjection(bson.M{"_id": 1}),
+	).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("user_not_found", "User not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_user_failed", "Failed to fetch user", err)
+	}
+	return &Profile{ID: userID, Links: []ProfileLink{}}, nil
+}
+
+// GetUser returns a user's public profile with how many ideas they listed,
+// the likes those received and their follow counts
+func (h *Handler) GetUser(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	profile, err := findProfile(ctx, db, c.Param("id"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	result := UserProfile{Profile: *profile}
+	cursor, err := db.Collection("ideas").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: Live(bson.M{"author_id": profile.ID, "status": listedStatuses()})}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            nil,
+			"ideas_count":    bson.M{"$sum": 1},
+			"likes_received": bson.M{"$sum": "$likes_count"},
+		}}},
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
+		return
+	}
+	var totals []struct {
+		IdeasCount    int64 `bson:"ideas_count"`
+		LikesReceived int64 `bson:"likes_received"`
+	}
+	if err := cursor.All(ctx, &totals); err != nil {
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
+		return
+	}
+	if len(totals) > 0 {
+		result.IdeasCount = totals[0].IdeasCount
+		result.LikesReceived = totals[0].LikesReceived
+	}
+
+	if result.FollowCounts, err = followCounts(ctx, db, profile.ID); err != nil {
+		apperror.Abort(c, apperror.Internal("count_follows_failed", "Failed to count follows", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// UpdateUser edits the bio and links of the caller's profile. Admins can
+// edit anyone's.
+func (h *Handler) UpdateUser(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	userID := c.Param("id")
+	if userID != c.GetString("user_id") && !isAdmin(c) {
+		apperror.Abort(c, apperror.Forbidden("not_profile_owner", "You can only edit your own profile"))
+		return
+	}
+
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if _, err := findProfile(ctx, db, userID); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	now := time.Now()
+	set := bson.M{"updated_at": now}
+	// Authors not synced from Clerk yet have no stored profile. A zero
+	// synced_at has the ProfileSyncer fill in their name and avatar.
+	insert := bson.M{"display_name": "", "avatar_url": "", "created_at": now, "synced_at": time.Time{}}
+	if req.Bio != nil {
+		set["bio"] = strings.TrimSpace(*req.Bio)
+	} else {
+		insert["bio"] = ""
+	}
+	if req.Links != nil {
+		set["links"] = *req.Links
+	} else {
+		insert["links"] = []ProfileLink{}
+	}
+
+	var updated Profile
+	err = db.Collection("users").FindOneAndUpdate(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": set, "$setOnInsert": insert},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_user_failed", "Failed to update user", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// GetUserIdeas lists the ideas a user published, newest first
+func (h *Handler) GetUserIdeas(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	collection := db.Collection("ideas")
+	// Served by the author_id+created_at index
+	filter := Live(bson.M{"author_id": c.Param("id"), "status": listedStatuses()})
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_ideas_failed", "Failed to count ideas", err))
+		return
+	}
+
+	page, pageSize := pagination.Parse(c)
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+		return
+	}
+	ideas := []Idea{}
+	if err := cursor.All(ctx, &ideas); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+		return
+	}
+	refs := make([]*Idea, len(ideas))
+	for i := range ideas {
+		NormalizeStatus(&ideas[i])
+		refs[i] = &ideas[i]
+	}
+	EmbedAuthors(ctx, db, refs...)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       ideas,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// ProfileSyncer keeps profiles in sync with Clerk for users who don't sign
+// in, and creates them for authors who haven't signed in since profiles
+// were added
+type ProfileSyncer struct {
+	client   *mongo.Client
+	interval time.Duration
+}
+
+// NewProfileSyncer creates a syncer that runs every interval
+func NewProfileSyncer(client *mongo.Client, interval time.Duration) *ProfileSyncer {
+	return &ProfileSyncer{
+		client:   client,
+		interval: interval,
+	}
+}
+
+// Run syncs profiles until ctx is cancelled
+func (s *ProfileSyncer) Run(ctx context.Context) {
+	runPeriodically(ctx, s.interval, func(ctx context.Context) {
+		synced, err := s.SyncStale(ctx)
+		if err != nil {
+			log.Printf("Failed to sync profiles: %v", err)
+		}
+		if synced > 0 {
+			log.Printf("Synced %d profiles from Clerk", synced)
+		}
+	})
+}
+
+// SyncStale re-reads from Clerk the profiles older than profileMaxAge and
+// those of authors without one. It returns the number of profiles synced.
+func (s *ProfileSyncer) SyncStale(ctx context.Context) (int, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, profileSyncTimeout)
+	defer cancel()
+
+	db := s.client.Database(cfg.MongoDBConfig.Database)
+
+	stale, err := db.Collection("users").Find(ctx,
+		bson.M{"synced_at": bson.M{"$lt": time.Now().Add(-profileMaxAge)}},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	synced, err := s.syncAll(ctx, db, stale)
+	if err != nil {
+		return synced, err
+	}
+
+	missing, err := db.Collection("ideas").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: Live(bson.M{})}},
+		{{Key: "$group", Value: bson.M{"_id": "$author_id"}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"pipeline":     mongo.Pipeline{{{Key: "$project", Value: bson.M{"_id": 1}}}},
+			"as":           "profile",
+		}}},
+		{{Key: "$match", Value: bson.M{"profile": bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	}, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return synced, err
+	}
+	n, err := s.syncAll(ctx, db, missing)
+	return synced + n, err
+}
+
+// syncAll syncs the users whose IDs cursor yields, clerkBatch at a time
+func (s *ProfileSyncer) syncAll(ctx context.Context, db *mongo.Database, cursor *mongo.Cursor) (int, error) {
+	defer cursor.Close(ctx)
+
+	synced := 0
+	batch := make([]string, 0, clerkBatch)
+	flush := func() error {
+		n, err := syncProfiles(ctx, db, batch)
+		synced += n
+		batch = batch[:0]
+		return err
+	}
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return synced, err
+		}
+		batch = append(batch, doc.ID)
+		if len(batch) == clerkBatch {
+			if err := flush(); err != nil {
+				return synced, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return synced, err
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return synced, err
+		}
+	}
+	return synced, nil
+}
+
+// syncProfiles fetches up to clerkBatch users from Clerk and updates their
+// profiles. Users Clerk no longer knows, such as deleted ones, are marked as
+// synced so they are only retried once their profile is stale again.
+func syncProfiles(ctx context.Context, db *mongo.Database, ids []string) (int, error) {
+	params := &user.ListParams{UserIDs: ids}
+	params.Limit = clerk.Int64(int64(len(ids)))
+	users, err := user.List(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+
+	found := make(map[string]bool, len(users.Users))
+	for _, usr := range users.Users {
+		if err := upsertProfile(ctx, db, usr); err != nil {
+			return len(found), err
+		}
+		found[usr.ID] = true
+	}
+
+	var gone []string
+	for _, id := range ids {
+		if !found[id] {
+			gone = append(gone, id)
+		}
+	}
+	if len(gone) == 0 {
+		return len(found), nil
+	}
+	now := time.Now()
+	models := make([]mongo.WriteModel, len(gone))
+	for i, id := range gone {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id}).
+			SetUpdate(bson.M{
+				"$set": bson.M{"synced_at": now},
+				"$setOnInsert": bson.M{
+					"display_name": "",
+					"avatar_url":   "",
+					"bio":          "",
+					"links":        []ProfileLink{},
+					"created_at":   now,
+					"updated_at":   now,
+				},
+			}).
+			SetUpsert(true)
+	}
+	_, err = db.Collection("users").BulkWrite(ctx, models)
+	return len(found), err
+}
@@ -0,0 +1,668 @@
+package lists
+
+import (
+	"context"
+	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/ideas"
+	"ikurotime/backlog-go-backend/internal/pagination"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const maxSlugLength = 60
+
+// reservedSlugs are path segments under /v1/lists that can't be list slugs
+var reservedSlugs = map[string]bool{
+	"following": true,
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// List is a public, ordered and titled list of ideas curated by a user
+type List struct {
+	ID           bson.ObjectID   `bson:"_id,omitempty" json:"id"`
+	AuthorID     string          `bson:"author_id" json:"author_id"`
+	Title        string          `bson:"title" json:"title"`
+	Slug         string          `bson:"slug" json:"slug"`
+	Description  string          `bson:"description" json:"description"`
+	IdeaIDs      []bson.ObjectID `bson:"idea_ids" json:"idea_ids"`
+	LikesCount   int             `bson:"likes_count" json:"likes_count"`
+	FollowsCount int             `bson:"follows_count" json:"follows_count"`
+	CreatedAt    time.Time       `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time       `bson:"updated_at" json:"updated_at"`
+}
+
+// ListWithIdeas is a list with its ideas resolved in list order
+type ListWithIdeas struct {
+	List  `bson:",inline"`
+	Ideas []ideas.Idea `json:"ideas"`
+}
+
+// ListLike represents a user's like on a list
+type ListLike struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	UserID    string        `bson:"user_id"`
+	ListID    bson.ObjectID `bson:"list_id"`
+	CreatedAt time.Time     `bson:"created_at"`
+}
+
+// ListFollow represents a user following a list
+type ListFollow struct {
+	ID        bson.ObjectID `bson:"_id,omitempty"`
+	UserID    string        `bson:"user_id"`
+	ListID    bson.ObjectID `bson:"list_id"`
+	CreatedAt time.Time     `bson:"created_at"`
+}
+
+type createListRequest struct {
+	Title       string   `json:"title" binding:"required,min=3,max=120"`
+	Description string   `json:"description" binding:"max=2000"`
+	IdeaIDs     []string `json:"idea_ids" binding:"max=100"`
+}
+
+type updateListRequest struct {
+	Title       *string   `json:"title" binding:"omitempty,min=3,max=120"`
+	Description *string   `json:"description" binding:"omitempty,max=2000"`
+	IdeaIDs     *[]string `json:"idea_ids" binding:"omitempty,max=100"`
+}
+
+// Handler handles curated list HTTP requests
+type Handler struct {
+	client *mongo.Client
+}
+
+// NewHandler creates a new lists handler
+func NewHandler(client *mongo.Client) *Handler {
+	handler := &Handler{
+		client: client,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("Failed to load config: %v", err)
+		return handler
+	}
+
+	if err := handler.setupIndexes(ctx, client.Database(cfg.MongoDBConfig.Database)); err != nil {
+		log.Printf("Failed to setup list indexes: %v", err)
+	}
+
+	return handler
+}
+
+// setupIndexes creates the indexes used by list queries
+func (h *Handler) setupIndexes(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("lists").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "slug", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "author_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			Keys: bson.D{{Key: "idea_ids", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "title", Value: "text"},
+				{Key: "description", Value: "text"},
+			},
+			Options: options.Index().SetName("lists_text_search"),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{"list_likes", "list_follows"} {
+		_, err = db.Collection(name).Indexes().CreateMany(ctx, []mongo.IndexModel{
+			{
+				Keys: bson.D{
+					{Key: "user_id", Value: 1},
+					{Key: "list_id", Value: 1},
+				},
+				Options: options.Index().SetUnique(true),
+			},
+			{
+				Keys: bson.D{
+					{Key: "user_id", Value: 1},
+					{Key: "created_at", Value: -1},
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveIdea pulls a deleted idea out of every list that contains it
+func RemoveIdea(ctx context.Context, db *mongo.Database, ideaID bson.ObjectID) error {
+	_, err := db.Collection("lists").UpdateMany(ctx,
+		bson.M{"idea_ids": ideaID},
+		bson.M{
+			"$pull": bson.M{"idea_ids": ideaID},
+			"$set":  bson.M{"updated_at": time.Now()},
+		},
+	)
+	return err
+}
+
+// slugify turns a title into a lowercase, hyphen-separated URL segment
+func slugify(title string) string {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		slug = "list"
+	}
+	return slug
+}
+
+// parseIdeaIDs validates idea IDs, rejecting duplicates
+func parseIdeaIDs(ids []string) ([]bson.ObjectID, error) {
+	seen := make(map[bson.ObjectID]bool, len(ids))
+	out := make([]bson.ObjectID, 0, len(ids))
+	for i, id := range ids {
+		field := "idea_ids[" + strconv.Itoa(i) + "]"
+		oid, err := bson.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, apperror.Validation("invalid_idea_id", "Invalid idea ID", apperror.FieldError{
+				Field:   field,
+				Message: "must be a 24 character hex ObjectID",
+			})
+		}
+		if seen[oid] {
+			return nil, apperror.Validation("duplicate_idea", "An idea can only appear once in a list", apperror.FieldError{
+				Field:   field,
+				Message: "is a duplicate",
+			})
+		}
+		seen[oid] = true
+		out = append(out, oid)
+	}
+	return out, nil
+}
+
+// checkIdeasExist fails with not-found if any idea doesn't exist, is a draft or is in the trash
+func checkIdeasExist(ctx context.Context, db *mongo.Database, ids []bson.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	n, err := db.Collection("ideas").CountDocuments(ctx, ideas.Live(bson.M{
+		"_id":    bson.M{"$in": ids},
+		"status": bson.M{"$ne": ideas.StatusDraft},
+	}))
+	if err != nil {
+		return apperror.Internal("check_ideas_failed", "Failed to check ideas", err)
+	}
+	if n != int64(len(ids)) {
+		return apperror.NotFound("idea_not_found", "One or more ideas do not exist")
+	}
+	return nil
+}
+
+// findBySlug loads a list by its slug
+func findBySlug(ctx context.Context, db *mongo.Database, slug string) (*List, error) {
+	var list List
+	err := db.Collection("lists").FindOne(ctx, bson.M{"slug": slug}).Decode(&list)
+	if err == mongo.ErrNoDocuments {
+		return nil, apperror.NotFound("list_not_found", "List not found")
+	}
+	if err != nil {
+		return nil, apperror.Internal("fetch_list_failed", "Failed to fetch list", err)
+	}
+	return &list, nil
+}
+
+// findOwnedBySlug loads a list and checks the caller is its author
+func findOwnedBySlug(ctx context.Context, db *mongo.Database, slug, userID string) (*List, error) {
+	list, err := findBySlug(ctx, db, slug)
+	if err != nil {
+		return nil, err
+	}
+	if list.AuthorID != userID {
+		return nil, apperror.Forbidden("not_list_author", "Only the list author can change this list")
+	}
+	return list, nil
+}
+
+// GetAll lists public lists, optionally filtered by author or text search
+func (h *Handler) GetAll(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	collection := db.Collection("lists")
+
+	filter := bson.M{}
+	if authorID := c.Query("author_id"); authorID != "" {
+		filter["author_id"] = authorID
+	}
+	if search := c.Query("search"); search != "" {
+		filter["$text"] = bson.M{"$search": search}
+	}
+
+	sort := bson.D{{Key: "created_at", Value: -1}}
+	if c.Query("sort") == "popular" {
+		sort = bson.D{
+			{Key: "follows_count", Value: -1},
+			{Key: "likes_count", Value: -1},
+			{Key: "created_at", Value: -1},
+		}
+	}
+
+	page, pageSize := pagination.Parse(c)
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_lists_failed", "Failed to count lists", err))
+		return
+	}
+
+	opts := options.Find().
+		SetSort(sort).
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_lists_failed", "Failed to fetch lists", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var lists []List
+	if err := cursor.All(ctx, &lists); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_lists_failed", "Failed to decode lists", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       lists,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
+
+// GetOne returns a list by slug with its ideas in order.
+// Ideas that no longer exist are skipped.
+func (h *Handler) GetOne(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	list, err := findBySlug(ctx, db, c.Param("slug"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	result := ListWithIdeas{List: *list, Ideas: []ideas.Idea{}}
+	if len(list.IdeaIDs) > 0 {
+		cursor, err := db.Collection("ideas").Find(ctx, ideas.Live(bson.M{
+			"_id":    bson.M{"$in": list.IdeaIDs},
+			"status": bson.M{"$ne": ideas.StatusDraft},
+		}))
+		if err != nil {
+			apperror.Abort(c, apperror.Internal("fetch_ideas_failed", "Failed to fetch ideas", err))
+			return
+		}
+		defer cursor.Close(ctx)
+
+		var found []ideas.Idea
+		if err := cursor.All(ctx, &found); err != nil {
+			apperror.Abort(c, apperror.Internal("decode_ideas_failed", "Failed to decode ideas", err))
+			return
+		}
+
+		byID := make(map[bson.ObjectID]ideas.Idea, len(found))
+		for _, idea := range found {
+			byID[idea.ID] = idea
+		}
+		for _, id := range list.IdeaIDs {
+			if idea, ok := byID[id]; ok {
+				ideas.NormalizeStatus(&idea)
+				result.Ideas = append(result.Ideas, idea)
+			}
+		}
+		refs := make([]*ideas.Idea, len(result.Ideas))
+		for i := range result.Ideas {
+			refs[i] = &result.Ideas[i]
+		}
+		ideas.EmbedAuthors(ctx, db, refs...)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+// Create publishes a new list owned by the caller
+func (h *Handler) Create(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req createListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+	ideaIDs, err := parseIdeaIDs(req.IdeaIDs)
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+	if err := checkIdeasExist(ctx, db, ideaIDs); err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	now := time.Now()
+	list := List{
+		ID:          bson.NewObjectID(),
+		AuthorID:    c.GetString("user_id"),
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		IdeaIDs:     ideaIDs,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	// Fall back to a slug suffixed with part of the list ID when the
+	// plain slug is taken or reserved
+	base := slugify(list.Title)
+	candidates := []string{base, base + "-" + list.ID.Hex()[18:]}
+	for i, slug := range candidates {
+		if reservedSlugs[slug] {
+			continue
+		}
+		list.Slug = slug
+		_, err = db.Collection("lists").InsertOne(ctx, list)
+		if mongo.IsDuplicateKeyError(err) && i < len(candidates)-1 {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("create_list_failed", "Failed to create list", err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": list})
+}
+
+// Update changes a list's title, description or ordered ideas.
+// The slug is kept so existing links keep working.
+func (h *Handler) Update(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	var req updateListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperror.Abort(c, apperror.FromBinding(err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	list, err := findOwnedBySlug(ctx, db, c.Param("slug"), c.GetString("user_id"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	set := bson.M{"updated_at": time.Now()}
+	if req.Title != nil {
+		set["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		set["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.IdeaIDs != nil {
+		ideaIDs, err := parseIdeaIDs(*req.IdeaIDs)
+		if err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		if err := checkIdeasExist(ctx, db, ideaIDs); err != nil {
+			apperror.Abort(c, err)
+			return
+		}
+		set["idea_ids"] = ideaIDs
+	}
+
+	var updated List
+	err = db.Collection("lists").FindOneAndUpdate(ctx,
+		bson.M{"_id": list.ID},
+		bson.M{"$set": set},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_list_failed", "Failed to update list", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": updated})
+}
+
+// Delete removes a list along with its likes and follows
+func (h *Handler) Delete(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	list, err := findOwnedBySlug(ctx, db, c.Param("slug"), c.GetString("user_id"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	if _, err := db.Collection("lists").DeleteOne(ctx, bson.M{"_id": list.ID}); err != nil {
+		apperror.Abort(c, apperror.Internal("delete_list_failed", "Failed to delete list", err))
+		return
+	}
+	for _, name := range []string{"list_likes", "list_follows"} {
+		if _, err := db.Collection(name).DeleteMany(ctx, bson.M{"list_id": list.ID}); err != nil {
+			apperror.Abort(c, apperror.Internal("delete_list_failed", "Failed to delete list", err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "List deleted successfully"})
+}
+
+// Like handles liking a list
+func (h *Handler) Like(c *gin.Context) {
+	h.toggle(c, "list_likes", "likes_count", true, "List liked successfully")
+}
+
+// Unlike handles removing a like from a list
+func (h *Handler) Unlike(c *gin.Context) {
+	h.toggle(c, "list_likes", "likes_count", false, "List unliked successfully")
+}
+
+// Follow handles following a list
+func (h *Handler) Follow(c *gin.Context) {
+	h.toggle(c, "list_follows", "follows_count", true, "List followed successfully")
+}
+
+// Unfollow handles unfollowing a list
+func (h *Handler) Unfollow(c *gin.Context) {
+	h.toggle(c, "list_follows", "follows_count", false, "List unfollowed successfully")
+}
+
+// toggle adds or removes the caller's relation document in collName and keeps
+// the list's counter field in sync within a transaction
+func (h *Handler) toggle(c *gin.Context, collName, counter string, add bool, message string) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	list, err := findBySlug(ctx, db, c.Param("slug"))
+	if err != nil {
+		apperror.Abort(c, err)
+		return
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("start_transaction_failed", "Failed to start transaction", err))
+		return
+	}
+	defer session.EndSession(ctx)
+
+	filter := bson.M{"user_id": userID, "list_id": list.ID}
+	_, err = session.WithTransaction(ctx, func(sessCtx context.Context) (interface{}, error) {
+		coll := db.Collection(collName)
+		delta := 1
+		if add {
+			result, err := coll.UpdateOne(sessCtx, filter,
+				bson.M{"$setOnInsert": bson.M{"created_at": time.Now()}},
+				options.UpdateOne().SetUpsert(true),
+			)
+			if err != nil {
+				return nil, err
+			}
+			if result.UpsertedCount == 0 {
+				return nil, nil // Already exists
+			}
+		} else {
+			result, err := coll.DeleteOne(sessCtx, filter)
+			if err != nil {
+				return nil, err
+			}
+			if result.DeletedCount == 0 {
+				return nil, nil // Didn't exist
+			}
+			delta = -1
+		}
+
+		_, err := db.Collection("lists").UpdateOne(sessCtx,
+			bson.M{"_id": list.ID},
+			bson.M{"$inc": bson.M{counter: delta}},
+		)
+		return nil, err
+	})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("update_list_failed", "Failed to update list", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// GetFollowing lists the lists the caller follows, most recently followed first
+func (h *Handler) GetFollowing(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c, 10*time.Second)
+	defer cancel()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("load_config_failed", "Failed to load config", err))
+		return
+	}
+
+	userID := c.GetString("user_id")
+	db := h.client.Database(cfg.MongoDBConfig.Database)
+
+	page, pageSize := pagination.Parse(c)
+
+	pipeline := []bson.D{
+		{{Key: "$match", Value: bson.M{"user_id": userID}}},
+		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		{{Key: "$skip", Value: int64((page - 1) * pageSize)}},
+		{{Key: "$limit", Value: pageSize}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "lists",
+			"localField":   "list_id",
+			"foreignField": "_id",
+			"as":           "list",
+		}}},
+		{{Key: "$unwind", Value: "$list"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$list"}}},
+	}
+
+	cursor, err := db.Collection("list_follows").Aggregate(ctx, pipeline)
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("fetch_lists_failed", "Failed to fetch lists", err))
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var lists []List
+	if err := cursor.All(ctx, &lists); err != nil {
+		apperror.Abort(c, apperror.Internal("decode_lists_failed", "Failed to decode lists", err))
+		return
+	}
+
+	total, err := db.Collection("list_follows").CountDocuments(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		apperror.Abort(c, apperror.Internal("count_lists_failed", "Failed to count lists", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       lists,
+		"pagination": pagination.Meta(page, pageSize, total),
+	})
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+const namespace = "backlogg"
+
+// Auth failure reasons reported by the auth middleware
+const (
+	AuthMissingToken = "missing_token"
+	AuthInvalidJWT   = "invalid_jwt"
+	AuthUserLookup   = "user_lookup_failed"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests currently being served.",
+	})
+
+	authFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "failures_total",
+		Help:      "Authentication failures by reason.",
+	}, []string{"reason"})
+
+	mongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mongodb",
+		Name:      "command_duration_seconds",
+		Help:      "MongoDB command latency by command and outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "outcome"})
+
+	// IdeasCreated counts ideas added to the catalog, which happens when they
+	// are first published rather than when their draft is saved
+	IdeasCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ideas_created_total",
+		Help:      "Total number of ideas published for the first time.",
+	})
+
+	// Likes counts like and unlike actions
+	Likes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "likes_total",
+		Help:      "Total number of like actions by type.",
+	}, []string{"action"})
+
+	// Bookmarks counts bookmark and unbookmark actions
+	Bookmarks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bookmarks_total",
+		Help:      "Total number of bookmark actions by type.",
+	}, []string{"action"})
+)
+
+// Handler exposes the registered collectors in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	h := promhttp.Handler()
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// Middleware records request counts, latency and in-flight requests.
+// Routes are labelled by their template so that path parameters don't
+// blow up label cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		httpInFlight.Inc()
+		defer httpInFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// AuthFailure records a failed authentication attempt
+func AuthFailure(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
+}
+
+// CommandMonitor returns a MongoDB command monitor reporting command durations
+func CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "success").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "failure").Observe(e.Duration.Seconds())
+		},
+	}
+}
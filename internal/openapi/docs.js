@@ -0,0 +1,232 @@
+"use strict";
+
+// Renders /openapi.json without third-party code, so the page works offline
+// and under the strict Content-Security-Policy it is served with.
+(() => {
+  const methods = ["get", "post", "put", "patch", "delete"];
+  const root = document.getElementById("docs");
+  let spec;
+
+  // el creates an element with children; strings become text nodes
+  const el = (tag, attrs, ...children) => {
+    const node = document.createElement(tag);
+    for (const [name, value] of Object.entries(attrs || {})) {
+      node.setAttribute(name, value);
+    }
+    for (const child of children) {
+      if (child !== null && child !== undefined) {
+        node.append(child);
+      }
+    }
+    return node;
+  };
+
+  // resolve follows a local $ref such as #/components/schemas/Idea
+  const resolve = (obj) => {
+    if (!obj || !obj.$ref) {
+      return obj;
+    }
+    return obj.$ref
+      .replace(/^#\//, "")
+      .split("/")
+      .reduce((node, key) => (node ? node[key] : undefined), spec);
+  };
+
+  const refName = (ref) => ref.split("/").pop();
+
+  // typeOf describes a schema in one line, linking referenced schemas
+  const typeOf = (schema) => {
+    if (!schema) {
+      return el("span", { class: "muted" }, "any");
+    }
+    if (schema.$ref) {
+      const name = refName(schema.$ref);
+      return el("a", { href: "#schema-" + name }, name);
+    }
+    const span = el("span");
+    for (const key of ["oneOf", "anyOf", "allOf"]) {
+      if (schema[key]) {
+        schema[key].forEach((s, i) => {
+          if (i > 0) {
+            span.append(key === "allOf" ? " & " : " | ");
+          }
+          span.append(typeOf(s));
+        });
+        return span;
+      }
+    }
+    if (schema.type === "array" || (Array.isArray(schema.type) && schema.type.includes("array"))) {
+      span.append("array of ", typeOf(schema.items));
+      return span;
+    }
+    let type = Array.isArray(schema.type) ? schema.type.join(" | ") : schema.type || "object";
+    if (schema.format) {
+      type += " (" + schema.format + ")";
+    }
+    span.append(type);
+    if (schema.enum) {
+      span.append(": " + schema.enum.map((v) => JSON.stringify(v)).join(", "));
+    }
+    return span;
+  };
+
+  // schemaTree lists the properties of an object schema, nesting inline objects
+  const schemaTree = (schema, depth) => {
+    const resolved = resolve(schema);
+    if (!resolved || !resolved.properties || (schema.$ref && depth > 0)) {
+      return el("div", {}, typeOf(schema));
+    }
+    const required = new Set(resolved.required || []);
+    const list = el("ul", { class: "schema" });
+    for (const [name, prop] of Object.entries(resolved.properties)) {
+      const item = el("li", {}, el("code", {}, name), " ", typeOf(prop));
+      if (required.has(name)) {
+        item.append(" ", el("span", { class: "required" }, "required"));
+      }
+      const target = resolve(prop);
+      if (target && target.description) {
+        item.append(el("span", { class: "muted" }, " — " + target.description));
+      }
+      if (!prop.$ref && prop.properties) {
+        item.append(schemaTree(prop, depth + 1));
+      }
+      list.append(item);
+    }
+    return list;
+  };
+
+  const content = (body) => {
+    const wrap = el("div");
+    for (const [type, media] of Object.entries(body.content || {})) {
+      wrap.append(el("div", {}, el("code", {}, type), " ", typeOf(media.schema)));
+      if (media.schema && !media.schema.$ref) {
+        wrap.append(schemaTree(media.schema, 1));
+      }
+    }
+    return wrap;
+  };
+
+  const parameters = (params) => {
+    const table = el(
+      "table",
+      {},
+      el("tr", {}, el("th", {}, "Name"), el("th", {}, "In"), el("th", {}, "Type"), el("th", {}, "Description")),
+    );
+    for (const ref of params) {
+      const p = resolve(ref);
+      const name = el("td", {}, el("code", {}, p.name));
+      if (p.required) {
+        name.append(" ", el("span", { class: "required" }, "*"));
+      }
+      table.append(el("tr", {}, name, el("td", {}, p.in), el("td", {}, typeOf(p.schema)), el("td", {}, p.description || "")));
+    }
+    return table;
+  };
+
+  const operation = (path, method, op, shared) => {
+    const summary = el(
+      "summary",
+      {},
+      el("span", { class: "method " + method }, method),
+      el("span", { class: "path" }, path),
+      op.summary ? el("span", { class: "summary" }, " — " + op.summary) : null,
+    );
+    const secured = (op.security || spec.security || []).length > 0;
+    if (secured) {
+      summary.append(el("span", { class: "lock", title: "Requires authentication" }, "🔒"));
+    }
+
+    const body = el("div", { class: "body" });
+    if (op.description) {
+      body.append(el("p", {}, op.description));
+    }
+    const params = [...shared, ...(op.parameters || [])];
+    if (params.length > 0) {
+      body.append(el("h4", {}, "Parameters"), parameters(params));
+    }
+    if (op.requestBody) {
+      body.append(el("h4", {}, "Request body"), content(resolve(op.requestBody)));
+    }
+    body.append(el("h4", {}, "Responses"));
+    for (const [code, ref] of Object.entries(op.responses || {})) {
+      const res = resolve(ref);
+      body.append(el("div", {}, el("strong", {}, code), " " + (res.description || "")), content(res));
+    }
+    return el("details", { id: op.operationId || method + path }, summary, body);
+  };
+
+  const render = () => {
+    root.replaceChildren();
+    root.append(
+      el("h1", {}, spec.info.title),
+      el("div", { class: "version" }, "Version " + spec.info.version + " · ", el("a", { href: "/openapi.json" }, "openapi.json")),
+    );
+    if (spec.info.description) {
+      root.append(el("pre", {}, spec.info.description.trim()));
+    }
+
+    const byTag = new Map((spec.tags || []).map((t) => [t.name, []]));
+    for (const [path, item] of Object.entries(spec.paths || {})) {
+      for (const method of methods) {
+        const op = item[method];
+        if (!op) {
+          continue;
+        }
+        const tag = (op.tags || ["other"])[0];
+        if (!byTag.has(tag)) {
+          byTag.set(tag, []);
+        }
+        byTag.get(tag).push(operation(path, method, op, item.parameters || []));
+      }
+    }
+    for (const [tag, ops] of byTag) {
+      if (ops.length > 0) {
+        root.append(el("h2", {}, tag), ...ops);
+      }
+    }
+
+    const schemas = Object.entries((spec.components && spec.components.schemas) || {});
+    if (schemas.length > 0) {
+      root.append(el("h2", {}, "Schemas"));
+      for (const [name, schema] of schemas) {
+        root.append(
+          el(
+            "details",
+            { id: "schema-" + name },
+            el("summary", {}, el("span", { class: "path" }, name)),
+            el("div", { class: "body" }, schema.description ? el("p", {}, schema.description) : null, schemaTree(schema, 0)),
+          ),
+        );
+      }
+    }
+
+    // Open the operation or schema a link points at
+    const target = location.hash && document.getElementById(decodeURIComponent(location.hash.slice(1)));
+    if (target && target.tagName === "DETAILS") {
+      target.open = true;
+      target.scrollIntoView();
+    }
+  };
+
+  window.addEventListener("hashchange", () => {
+    const target = document.getElementById(decodeURIComponent(location.hash.slice(1)));
+    if (target && target.tagName === "DETAILS") {
+      target.open = true;
+    }
+  });
+
+  fetch("/openapi.json")
+    .then((res) => {
+      if (!res.ok) {
+        throw new Error("GET /openapi.json returned " + res.status);
+      }
+      return res.json();
+    })
+    .then((doc) => {
+      spec = doc;
+      render();
+    })
+    .catch((err) => {
+      root.replaceChildren(el("p", { class: "error" }, "Failed to load the API document: " + err.message));
+    });
+})();
@@ -0,0 +1,107 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+//go:embed docs.html
+var docsHTML string
+
+//go:embed docs.js
+var docsJS string
+
+//go:embed docs.css
+var docsCSS string
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+	specErr  error
+
+	docsOnce sync.Once
+	docsPage []byte
+	docsCSP  string
+)
+
+// Document is the subset of an OpenAPI document needed to inspect its paths
+type Document struct {
+	Paths map[string]map[string]interface{} `json:"paths"`
+}
+
+// Spec returns the OpenAPI document rendered as JSON
+func Spec() ([]byte, error) {
+	specOnce.Do(func() {
+		var doc map[string]interface{}
+		if specErr = yaml.Unmarshal(specYAML, &doc); specErr != nil {
+			return
+		}
+		specJSON, specErr = json.Marshal(doc)
+	})
+	return specJSON, specErr
+}
+
+// Parse decodes the paths of the OpenAPI document
+func Parse() (*Document, error) {
+	b, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Handler serves the OpenAPI document
+func Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b, err := Spec()
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		c.Data(http.StatusOK, "application/json", b)
+	}
+}
+
+// docs inlines the script and stylesheet of the documentation UI into its
+// page, and builds a Content-Security-Policy that allows only them by hash.
+// Nothing is loaded from third parties, so the UI also works offline.
+func docs() ([]byte, string) {
+	docsOnce.Do(func() {
+		page := strings.NewReplacer("{{style}}", docsCSS, "{{script}}", docsJS).Replace(docsHTML)
+		docsPage = []byte(page)
+		docsCSP = "default-src 'none'; connect-src 'self'; img-src 'self' data:; " +
+			"script-src '" + sourceHash(docsJS) + "'; style-src '" + sourceHash(docsCSS) + "'; " +
+			"base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+	})
+	return docsPage, docsCSP
+}
+
+// sourceHash is the CSP hash source of an inline script or style
+func sourceHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// DocsHandler serves the interactive documentation UI
+func DocsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, csp := docs()
+		c.Header("Content-Security-Policy", csp)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	}
+}
@@ -0,0 +1,47 @@
+package pagination
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Parse reads the page and size query parameters, falling back
+// to the defaults for missing or out-of-range values
+func Parse(c *gin.Context) (page, pageSize int) {
+	page = 1
+	pageSize = defaultPageSize
+
+	if p := c.Query("page"); p != "" {
+		if parsedPage, err := strconv.Atoi(p); err == nil && parsedPage > 0 {
+			page = parsedPage
+		}
+	}
+	if size := c.Query("size"); size != "" {
+		if parsedSize, err := strconv.Atoi(size); err == nil && parsedSize > 0 && parsedSize <= maxPageSize {
+			pageSize = parsedSize
+		}
+	}
+
+	return page, pageSize
+}
+
+// Meta builds the pagination object returned with list responses
+func Meta(page, pageSize int, total int64) gin.H {
+	totalPages := int(math.Ceil(float64(total) / float64(pageSize)))
+
+	return gin.H{
+		"current_page": page,
+		"page_size":    pageSize,
+		"total_items":  total,
+		"total_pages":  totalPages,
+		"has_next":     page < totalPages,
+		"has_prev":     page > 1,
+	}
+}
@@ -0,0 +1,43 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header is the header used to accept and return request IDs
+const Header = "X-Request-ID"
+
+// ContextKey is the gin context key holding the request ID
+const ContextKey = "request_id"
+
+// Middleware reuses an incoming X-Request-ID or generates a new one,
+// stores it on the context and echoes it in the response
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if id == "" || len(id) > 128 {
+			id = generate()
+		}
+
+		c.Set(ContextKey, id)
+		c.Header(Header, id)
+
+		c.Next()
+	}
+}
+
+// Get returns the request ID stored on the context
+func Get(c *gin.Context) string {
+	return c.GetString(ContextKey)
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -1,11 +1,22 @@
 package router
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/apperror"
+	"ikurotime/backlog-go-backend/internal/health"
 	"ikurotime/backlog-go-backend/internal/ideas"
+	"ikurotime/backlog-go-backend/internal/lists"
+	"ikurotime/backlog-go-backend/internal/metrics"
+	"ikurotime/backlog-go-backend/internal/openapi"
+	"ikurotime/backlog-go-backend/internal/requestid"
+	"ikurotime/backlog-go-backend/internal/tracing"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2/jwt"
 	"github.com/clerk/clerk-sdk-go/v2/user"
@@ -16,22 +27,44 @@ import (
 type Router struct {
 	engine *gin.Engine
 	client *mongo.Client
+	health *health.Checker
+	ideas  *ideas.Handler
 }
 
 func NewRouter(client *mongo.Client) *Router {
 	r := &Router{
 		engine: gin.Default(),
 		client: client,
+		health: health.NewChecker(2 * time.Second),
 	}
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	r.health.Register("mongodb", func(ctx context.Context) error {
+		return client.Ping(ctx, nil)
+	})
+	r.health.Register("auth", func(ctx context.Context) error {
+		if cfg.ClerkConfig.ApiKey == "" {
+			return errors.New("clerk api key is not configured")
+		}
+		return nil
+	})
+
+	// Let handlers derive contexts from *gin.Context and still see the request span
+	r.engine.ContextWithFallback = true
+	r.engine.Use(tracing.Middleware(cfg.TracingConfig.ServiceName))
+	r.engine.Use(metrics.Middleware())
+	r.engine.Use(requestid.Middleware())
+	r.engine.Use(apperror.Middleware())
+
 	// Setup CORS middleware
 	r.engine.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", cfg.Server.AllowedOrigin)
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -42,6 +75,10 @@ func NewRouter(client *mongo.Client) *Router {
 		c.Next()
 	})
 
+	r.engine.NoRoute(func(c *gin.Context) {
+		apperror.Abort(c, apperror.NotFound("route_not_found", "Route not found"))
+	})
+
 	r.setupRoutes()
 
 	return r
@@ -54,81 +91,225 @@ func (r *Router) setupRoutes() {
 
 func (r *Router) setupPublicRoutes() {
 	r.engine.GET("/health", r.handleHealth())
+	r.engine.GET("/livez", r.health.Live())
+	r.engine.GET("/readyz", r.health.Ready())
+	r.engine.GET("/metrics", metrics.Handler())
+	r.engine.GET("/openapi.json", openapi.Handler())
+	r.engine.GET("/docs", openapi.DocsHandler())
 }
 
 func (r *Router) setupProtectedRoutes() {
 	api := r.engine.Group("/v1")
 	{
+		ideasHandler := ideas.NewHandler(r.client)
+		r.ideas = ideasHandler
+		r.health.Register("indexes", ideasHandler.CheckIndexes)
+
 		ideasGroup := api.Group("/ideas")
 		{
-			handler := ideas.NewHandler(r.client)
-			ideasGroup.GET("", handler.GetAll)
-			ideasGroup.GET("/:id", handler.GetOne)
-			ideasGroup.POST("/:id/like", r.requireAuth(), handler.LikeIdea)
-			ideasGroup.DELETE("/:id/like", r.requireAuth(), handler.UnlikeIdea)
-			ideasGroup.POST("/:id/bookmark", r.requireAuth(), handler.BookmarkIdea)
-			ideasGroup.DELETE("/:id/bookmark", r.requireAuth(), handler.UnbookmarkIdea)
-			ideasGroup.GET("/bookmarks", r.requireAuth(), handler.GetBookmarkedIdeas)
+			ideasGroup.GET("", ideasHandler.GetAll)
+			ideasGroup.POST("", r.requireAuth(), ideasHandler.CreateIdea)
+			ideasGroup.GET("/drafts", r.requireAuth(), ideasHandler.GetDrafts)
+			ideasGroup.POST("/duplicates", r.requireAuth(), ideasHandler.CheckDuplicates)
+			ideasGroup.GET("/:id", r.optionalAuth(), ideasHandler.GetOne)
+			ideasGroup.PUT("/:id", r.requireAuth(), ideasHandler.UpdateIdea)
+			ideasGroup.DELETE("/:id", r.requireAuth(), ideasHandler.DeleteIdea)
+			ideasGroup.POST("/:id/restore", r.requireAuth(), ideasHandler.RestoreIdea)
+			ideasGroup.PUT("/:id/status", r.requireAuth(), ideasHandler.SetStatus)
+			ideasGroup.GET("/:id/revisions", r.optionalAuth(), ideasHandler.GetRevisions)
+			ideasGroup.GET("/:id/revisions/diff", r.optionalAuth(), ideasHandler.DiffRevisions)
+			ideasGroup.POST("/:id/revisions/:rev/restore", r.requireAuth(), ideasHandler.RestoreRevision)
+			ideasGroup.POST("/:id/details", r.requireAuth(), ideasHandler.CreateDetails)
+			ideasGroup.PUT("/:id/details", r.requireAuth(), ideasHandler.UpdateDetails)
+			ideasGroup.POST("/:id/like", r.requireAuth(), ideasHandler.LikeIdea)
+			ideasGroup.DELETE("/:id/like", r.requireAuth(), ideasHandler.UnlikeIdea)
+			ideasGroup.POST("/:id/bookmark", r.requireAuth(), ideasHandler.BookmarkIdea)
+			ideasGroup.DELETE("/:id/bookmark", r.requireAuth(), ideasHandler.UnbookmarkIdea)
+			ideasGroup.PUT("/:id/bookmark", r.requireAuth(), ideasHandler.FileBookmark)
+			ideasGroup.POST("/:id/dismiss", r.requireAuth(), ideasHandler.DismissIdea)
+			ideasGroup.DELETE("/:id/dismiss", r.requireAuth(), ideasHandler.UndismissIdea)
+			ideasGroup.POST("/:id/fork", r.requireAuth(), ideasHandler.ForkIdea)
+			ideasGroup.GET("/:id/similar", r.optionalAuth(), ideasHandler.GetSimilar)
+			ideasGroup.POST("/:id/merge", r.requireAuth(), ideasHandler.MergeIdea)
+			ideasGroup.GET("/:id/forks", r.optionalAuth(), ideasHandler.GetForks)
+			ideasGroup.GET("/:id/lineage", r.optionalAuth(), ideasHandler.GetLineage)
+			ideasGroup.GET("/:id/builds", r.optionalAuth(), ideasHandler.GetBuilds)
+			ideasGroup.POST("/:id/build", r.requireAuth(), ideasHandler.ClaimIdea)
+			ideasGroup.PUT("/:id/build", r.requireAuth(), ideasHandler.UpdateBuild)
+			ideasGroup.DELETE("/:id/build", r.requireAuth(), ideasHandler.UnclaimIdea)
+			ideasGroup.DELETE("/:id/comments/:commentId", r.requireAuth(), ideasHandler.DeleteComment)
+			ideasGroup.POST("/:id/comments/:commentId/restore", r.requireAuth(), ideasHandler.RestoreComment)
+			ideasGroup.GET("/bookmarks", r.requireAuth(), ideasHandler.GetBookmarkedIdeas)
 		}
-	}
-}
 
-func (r *Router) requireAuth() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var sessionToken string
+		api.GET("/feed", r.requireAuth(), ideasHandler.GetFeed)
+		api.GET("/feed/following", r.requireAuth(), ideasHandler.GetFollowingFeed)
+		api.GET("/follows", r.requireAuth(), ideasHandler.ListFollows)
+		api.GET("/search/suggest", ideasHandler.Suggest)
 
-		// First try to get token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader != "" {
-			sessionToken = strings.TrimPrefix(authHeader, "Bearer ")
+		savedSearchesGroup := api.Group("/saved-searches", r.requireAuth())
+		{
+			savedSearchesGroup.GET("", ideasHandler.ListSavedSearches)
+			savedSearchesGroup.POST("", ideasHandler.CreateSavedSearch)
+			savedSearchesGroup.GET("/:id", ideasHandler.GetSavedSearch)
+			savedSearchesGroup.PUT("/:id", ideasHandler.UpdateSavedSearch)
+			savedSearchesGroup.DELETE("/:id", ideasHandler.DeleteSavedSearch)
+			savedSearchesGroup.GET("/:id/ideas", ideasHandler.GetSavedSearchIdeas)
 		}
 
-		// If no token in header, try to get it from cookie
-		if sessionToken == "" {
-			cookie, err := c.Cookie("__session")
-			if err == nil && cookie != "" {
-				sessionToken = cookie
-			}
+		notificationsGroup := api.Group("/notifications", r.requireAuth())
+		{
+			notificationsGroup.GET("", ideasHandler.ListNotifications)
+			notificationsGroup.POST("/read", ideasHandler.MarkNotificationsRead)
 		}
 
-		if sessionToken == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error":   "Authentication failed",
-				"message": "Missing authentication token",
-			})
-			return
+		tagsGroup := api.Group("/tags")
+		{
+			tagsGroup.GET("", ideasHandler.GetTags)
+			tagsGroup.POST("", r.requireAuth(), ideasHandler.CreateTag)
+			tagsGroup.GET("/:slug", ideasHandler.GetTag)
+			tagsGroup.PUT("/:slug", r.requireAuth(), ideasHandler.UpdateTag)
+			tagsGroup.POST("/:slug/merge", r.requireAuth(), ideasHandler.MergeTag)
+			tagsGroup.POST("/:slug/follow", r.requireAuth(), ideasHandler.FollowTag)
+			tagsGroup.DELETE("/:slug/follow", r.requireAuth(), ideasHandler.UnfollowTag)
 		}
 
-		claims, err := jwt.Verify(c.Request.Context(), &jwt.VerifyParams{
-			Token: sessionToken,
-		})
-		if err != nil {
-			log.Printf("JWT verification failed: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error":   "Authentication failed",
-				"message": "Invalid authentication token",
-			})
+		usersGroup := api.Group("/users")
+		{
+			usersGroup.GET("/:id", ideasHandler.GetUser)
+			usersGroup.PUT("/:id", r.requireAuth(), ideasHandler.UpdateUser)
+			usersGroup.GET("/:id/ideas", ideasHandler.GetUserIdeas)
+			usersGroup.GET("/:id/follow-counts", ideasHandler.GetFollowCounts)
+			usersGroup.POST("/:id/follow", r.requireAuth(), ideasHandler.FollowAuthor)
+			usersGroup.DELETE("/:id/follow", r.requireAuth(), ideasHandler.UnfollowAuthor)
+		}
+
+		collectionsGroup := api.Group("/collections", r.requireAuth())
+		{
+			collectionsGroup.GET("", ideasHandler.ListCollections)
+			collectionsGroup.POST("", ideasHandler.CreateCollection)
+			collectionsGroup.PUT("/order", ideasHandler.ReorderCollections)
+			collectionsGroup.PUT("/:id", ideasHandler.RenameCollection)
+			collectionsGroup.DELETE("/:id", ideasHandler.DeleteCollection)
+			collectionsGroup.GET("/:id/ideas", ideasHandler.GetCollectionIdeas)
+		}
+
+		trashGroup := api.Group("/trash", r.requireAuth())
+		{
+			trashGroup.GET("/ideas", ideasHandler.GetTrashedIdeas)
+			trashGroup.GET("/comments", ideasHandler.GetTrashedComments)
+		}
+
+		listsGroup := api.Group("/lists")
+		{
+			handler := lists.NewHandler(r.client)
+			listsGroup.GET("", handler.GetAll)
+			listsGroup.POST("", r.requireAuth(), handler.Create)
+			listsGroup.GET("/following", r.requireAuth(), handler.GetFollowing)
+			listsGroup.GET("/:slug", handler.GetOne)
+			listsGroup.PUT("/:slug", r.requireAuth(), handler.Update)
+			listsGroup.DELETE("/:slug", r.requireAuth(), handler.Delete)
+			listsGroup.POST("/:slug/like", r.requireAuth(), handler.Like)
+			listsGroup.DELETE("/:slug/like", r.requireAuth(), handler.Unlike)
+			listsGroup.POST("/:slug/follow", r.requireAuth(), handler.Follow)
+			listsGroup.DELETE("/:slug/follow", r.requireAuth(), handler.Unfollow)
+		}
+	}
+}
+
+func (r *Router) requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionToken := sessionToken(c)
+		if sessionToken == "" {
+			metrics.AuthFailure(metrics.AuthMissingToken)
+			apperror.Abort(c, apperror.Unauthorized("missing_token", "Missing authentication token"))
 			return
 		}
 
-		usr, err := user.Get(c.Request.Context(), claims.Subject)
-		if err != nil {
-			log.Printf("Failed to get user information: %v", err)
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error":   "Authentication failed",
-				"message": "Failed to get user information",
-			})
+		if err := r.authenticate(c, sessionToken); err != nil {
+			apperror.Abort(c, err)
 			return
 		}
 
-		c.Set("user_id", usr.ID)
-		c.Set("user_banned", usr.Banned)
-		c.Set("user_email", usr.EmailAddresses[0].EmailAddress)
+		c.Next()
+	}
+}
+
+// optionalAuth identifies the caller when a session token is present but lets
+// anonymous requests, and requests with an unusable token, through
+func (r *Router) optionalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sessionToken := sessionToken(c); sessionToken != "" {
+			_ = r.authenticate(c, sessionToken)
+		}
 
 		c.Next()
 	}
 }
 
+// sessionToken reads the Clerk session token from the request
+func sessionToken(c *gin.Context) string {
+	var sessionToken string
+
+	// First try to get token from Authorization header
+	authHeader := c.GetHeader("Authorization")
+	if authHeader != "" {
+		sessionToken = strings.TrimPrefix(authHeader, "Bearer ")
+	}
+
+	// If no token in header, try to get it from cookie
+	if sessionToken == "" {
+		cookie, err := c.Cookie("__session")
+		if err == nil && cookie != "" {
+			sessionToken = cookie
+		}
+	}
+
+	return sessionToken
+}
+
+// authenticate verifies the session token and stores the user on the context
+func (r *Router) authenticate(c *gin.Context, sessionToken string) *apperror.Error {
+	ctx, span := tracing.Start(c.Request.Context(), "auth.jwt.verify")
+	claims, err := jwt.Verify(ctx, &jwt.VerifyParams{
+		Token: sessionToken,
+	})
+	tracing.End(span, err)
+	if err != nil {
+		log.Printf("JWT verification failed: %v", err)
+		metrics.AuthFailure(metrics.AuthInvalidJWT)
+		return apperror.Unauthorized("invalid_token", "Invalid authentication token")
+	}
+
+	ctx, span = tracing.Start(c.Request.Context(), "clerk.user.get")
+	usr, err := user.Get(ctx, claims.Subject)
+	tracing.End(span, err)
+	if err != nil {
+		log.Printf("Failed to get user information: %v", err)
+		metrics.AuthFailure(metrics.AuthUserLookup)
+		return apperror.Unauthorized("user_lookup_failed", "Failed to get user information")
+	}
+
+	c.Set("user_id", usr.ID)
+	c.Set("user_banned", usr.Banned)
+	c.Set("user_email", usr.EmailAddresses[0].EmailAddress)
+	c.Set("user_role", userRole(usr.PublicMetadata))
+	r.ideas.SyncProfile(c.Request.Context(), usr)
+
+	return nil
+}
+
+// userRole reads the role (e.g. "admin") from the Clerk user's public metadata
+func userRole(publicMetadata json.RawMessage) string {
+	var metadata struct {
+		Role string `json:"role"`
+	}
+	if len(publicMetadata) == 0 || json.Unmarshal(publicMetadata, &metadata) != nil {
+		return ""
+	}
+	return metadata.Role
+}
+
 func (r *Router) handleHealth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -141,3 +322,11 @@ func (r *Router) handleHealth() gin.HandlerFunc {
 func (r *Router) GetEngine() *gin.Engine {
 	return r.engine
 }
+
+func (r *Router) GetHealth() *health.Checker {
+	return r.health
+}
+
+func (r *Router) GetIdeas() *ideas.Handler {
+	return r.ideas
+}
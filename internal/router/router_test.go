@@ -0,0 +1,46 @@
+package router
+
+import (
+	"ikurotime/backlog-go-backend/internal/openapi"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("APP_ENV", "template")
+
+	// No server is needed: index setup fails fast and only gets logged
+	client, err := mongo.Connect(options.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(50 * time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := openapi.Parse()
+	if err != nil {
+		t.Fatalf("parse openapi spec: %v", err)
+	}
+
+	r := NewRouter(client)
+	for _, route := range r.GetEngine().Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		ops, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("route %s %s: path %s missing from openapi spec", route.Method, route.Path, path)
+			continue
+		}
+		if _, ok := ops[strings.ToLower(route.Method)]; !ok {
+			t.Errorf("route %s %s: method missing from openapi spec", route.Method, route.Path)
+		}
+	}
+}
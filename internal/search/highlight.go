@@ -0,0 +1,101 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Markers wrapped around matched words in highlighted text
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+	ellipsis  = "…"
+)
+
+// minPrefix is the shortest query word matched as a prefix, so "build"
+// highlights "builder" the way the stemming text index matches it
+const minPrefix = 4
+
+type span struct {
+	start, end int
+}
+
+// words lowercases text and splits it into words
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// matches finds the words of text, as rune offsets, matching any of terms
+func matches(text []rune, terms []string) []span {
+	var spans []span
+	for i := 0; i < len(text); {
+		if !unicode.IsLetter(text[i]) && !unicode.IsNumber(text[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(text) && (unicode.IsLetter(text[i]) || unicode.IsNumber(text[i])) {
+			i++
+		}
+		word := strings.ToLower(string(text[start:i]))
+		for _, term := range terms {
+			if word == term || (len(term) >= minPrefix && strings.HasPrefix(word, term)) {
+				spans = append(spans, span{start, i})
+				break
+			}
+		}
+	}
+	return spans
+}
+
+// Highlight returns an HTML-escaped excerpt of text of about width runes
+// around the first word matching terms, with matches wrapped in <mark>.
+// Text without matches is excerpted from the start.
+func Highlight(text string, terms []string, width int) string {
+	rs := []rune(text)
+	spans := matches(rs, terms)
+
+	start, end := 0, len(rs)
+	if len(rs) > width {
+		if len(spans) > 0 {
+			start = max(spans[0].start-width/3, 0)
+		}
+		end = min(start+width, len(rs))
+		start = max(end-width, 0)
+		// Don't cut words in half, unless the excerpt is a single long word
+		s, e := start, end
+		for s > 0 && s < e && !unicode.IsSpace(rs[s-1]) {
+			s++
+		}
+		for e < len(rs) && e > s && !unicode.IsSpace(rs[e]) {
+			e--
+		}
+		if s < e {
+			start, end = s, e
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString(ellipsis)
+	}
+	pos := start
+	for _, s := range spans {
+		if s.start < start || s.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(rs[pos:s.start])))
+		b.WriteString(markOpen)
+		b.WriteString(html.EscapeString(string(rs[s.start:s.end])))
+		b.WriteString(markClose)
+		pos = s.end
+	}
+	b.WriteString(html.EscapeString(string(rs[pos:end])))
+	if end < len(rs) {
+		b.WriteString(ellipsis)
+	}
+	return strings.TrimSpace(b.String())
+}
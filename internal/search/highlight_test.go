@@ -0,0 +1,108 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{
+			name:  "no match",
+			text:  "Build a CLI",
+			terms: []string{"electron"},
+			width: 100,
+			want:  "Build a CLI",
+		},
+		{
+			name:  "match is case insensitive",
+			text:  "Build a CLI tool",
+			terms: []string{"cli"},
+			width: 100,
+			want:  "Build a <mark>CLI</mark> tool",
+		},
+		{
+			name:  "every occurrence",
+			text:  "Todo app, the todo way",
+			terms: []string{"todo"},
+			width: 100,
+			want:  "<mark>Todo</mark> app, the <mark>todo</mark> way",
+		},
+		{
+			name:  "long terms match as prefixes",
+			text:  "Builders build",
+			terms: []string{"build"},
+			width: 100,
+			want:  "<mark>Builders</mark> <mark>build</mark>",
+		},
+		{
+			name:  "short terms match whole words only",
+			text:  "apple app",
+			terms: []string{"app"},
+			width: 100,
+			want:  "apple <mark>app</mark>",
+		},
+		{
+			name:  "html is escaped",
+			text:  "<b>todo</b> & more",
+			terms: []string{"todo"},
+			width: 100,
+			want:  "&lt;b&gt;<mark>todo</mark>&lt;/b&gt; &amp; more",
+		},
+		{
+			name:  "offsets count runes",
+			text:  "Café crème brûlée",
+			terms: []string{"crème"},
+			width: 100,
+			want:  "Café <mark>crème</mark> brûlée",
+		},
+		{
+			name:  "excerpt from the start without a match",
+			text:  "alpha beta gamma delta epsilon",
+			terms: []string{"zeta"},
+			width: 12,
+			want:  "alpha beta…",
+		},
+		{
+			name:  "single long word is cut",
+			text:  "supercalifragilistic",
+			terms: nil,
+			width: 5,
+			want:  "super…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Highlight(tt.text, tt.terms, tt.width); got != tt.want {
+				t.Errorf("Highlight(%q, %v, %d) = %q, want %q", tt.text, tt.terms, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightExcerptsAroundFirstMatch(t *testing.T) {
+	text := "one two three four five six seven eight nine ten eleven twelve target thirteen fourteen fifteen sixteen seventeen"
+	got := Highlight(text, []string{"target"}, 40)
+
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Highlight() = %q, want an excerpt cut on both ends", got)
+	}
+	if !strings.Contains(got, "<mark>target</mark>") {
+		t.Errorf("Highlight() = %q, want the match in the excerpt", got)
+	}
+	plain := strings.NewReplacer("…", "", "<mark>", "", "</mark>", "").Replace(got)
+	if n := len([]rune(plain)); n > 40 {
+		t.Errorf("excerpt %q is %d runes, want at most 40", plain, n)
+	}
+	// Words aren't cut in half
+	if !strings.Contains(text, plain) || !strings.Contains(" "+text+" ", " "+plain+" ") {
+		t.Errorf("excerpt %q doesn't start and end on word boundaries", plain)
+	}
+}
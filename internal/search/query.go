@@ -0,0 +1,268 @@
+// Package search parses the idea search query language and highlights matches
+package search
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// dateLayout is the format of dates in created: qualifiers
+const dateLayout = "2006-01-02"
+
+const day = 24 * time.Hour
+
+// Qualifiers that scope a search term to a field
+const (
+	QualifierTag        = "tag"
+	QualifierDifficulty = "difficulty"
+	QualifierAuthor     = "author"
+	QualifierCreated    = "created"
+)
+
+var qualifiers = map[string]bool{
+	QualifierTag:        true,
+	QualifierDifficulty: true,
+	QualifierAuthor:     true,
+	QualifierCreated:    true,
+}
+
+// Query is a parsed search such as
+//
+//	"todo app" cli -electron tag:go difficulty:beginner created:>2025-01-01
+//
+// Free text is matched against title and description, qualifiers against
+// their field. A leading - excludes a word, phrase or qualifier value.
+type Query struct {
+	Terms           []string
+	Phrases         []string
+	ExcludedTerms   []string
+	ExcludedPhrases []string
+
+	Tags                 []string
+	ExcludedTags         []string
+	Difficulties         []string
+	ExcludedDifficulties []string
+	Authors              []string
+	ExcludedAuthors      []string
+
+	// CreatedFrom is inclusive and CreatedTo exclusive
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+type token struct {
+	negated   bool
+	qualifier string
+	value     string
+	quoted    bool
+}
+
+// Parse reads a search query. Unknown qualifiers are searched as plain
+// words; malformed dates are an error.
+func Parse(q string) (*Query, error) {
+	query := &Query{}
+	for _, t := range lex(q) {
+		if err := query.add(t); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
+
+func (q *Query) add(t token) error {
+	switch t.qualifier {
+	case QualifierTag:
+		appendTo(t.negated, &q.ExcludedTags, &q.Tags, t.value)
+	case QualifierDifficulty:
+		appendTo(t.negated, &q.ExcludedDifficulties, &q.Difficulties, strings.ToLower(t.value))
+	case QualifierAuthor:
+		appendTo(t.negated, &q.ExcludedAuthors, &q.Authors, t.value)
+	case QualifierCreated:
+		if t.negated {
+			return errors.New("created: can't be excluded")
+		}
+		return q.addCreated(t.value)
+	default:
+		if t.quoted {
+			appendTo(t.negated, &q.ExcludedPhrases, &q.Phrases, t.value)
+			return nil
+		}
+		value := strings.TrimLeft(t.value, "-")
+		if value != "" {
+			appendTo(t.negated, &q.ExcludedTerms, &q.Terms, value)
+		}
+	}
+	return nil
+}
+
+func appendTo(negated bool, excluded, included *[]string, value string) {
+	if negated {
+		*excluded = append(*excluded, value)
+	} else {
+		*included = append(*included, value)
+	}
+}
+
+// addCreated narrows the creation date range. Dates are whole UTC days:
+// >d starts the day after d, <=d ends with d, and a..b covers both days.
+func (q *Query) addCreated(value string) error {
+	var from, to *time.Time
+	switch {
+	case strings.HasPrefix(value, ">="):
+		d, err := parseDate(value[2:])
+		if err != nil {
+			return err
+		}
+		from = &d
+	case strings.HasPrefix(value, "<="):
+		d, err := parseDate(value[2:])
+		if err != nil {
+			return err
+		}
+		end := d.Add(day)
+		to = &end
+	case strings.HasPrefix(value, ">"):
+		d, err := parseDate(value[1:])
+		if err != nil {
+			return err
+		}
+		start := d.Add(day)
+		from = &start
+	case strings.HasPrefix(value, "<"):
+		d, err := parseDate(value[1:])
+		if err != nil {
+			return err
+		}
+		to = &d
+	case strings.Contains(value, ".."):
+		first, last, _ := strings.Cut(value, "..")
+		start, err := parseDate(first)
+		if err != nil {
+			return err
+		}
+		d, err := parseDate(last)
+		if err != nil {
+			return err
+		}
+		end := d.Add(day)
+		from, to = &start, &end
+	default:
+		d, err := parseDate(value)
+		if err != nil {
+			return err
+		}
+		end := d.Add(day)
+		from, to = &d, &end
+	}
+
+	if from != nil && (q.CreatedFrom == nil || from.After(*q.CreatedFrom)) {
+		q.CreatedFrom = from
+	}
+	if to != nil && (q.CreatedTo == nil || to.Before(*q.CreatedTo)) {
+		q.CreatedTo = to
+	}
+	return nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, errors.New("created: dates must look like 2025-01-31, got " + value)
+	}
+	return d, nil
+}
+
+// lex splits a query into words, quoted phrases and qualifier:value pairs,
+// each optionally prefixed with -. An unterminated quote runs to the end.
+func lex(q string) []token {
+	rs := []rune(q)
+	var tokens []token
+	i := 0
+	for i < len(rs) {
+		for i < len(rs) && unicode.IsSpace(rs[i]) {
+			i++
+		}
+		if i == len(rs) {
+			break
+		}
+
+		var t token
+		if rs[i] == '-' && i+1 < len(rs) && !unicode.IsSpace(rs[i+1]) {
+			t.negated = true
+			i++
+		}
+
+		// A qualifier is a known name directly followed by : and a value
+		start := i
+		for i < len(rs) && !unicode.IsSpace(rs[i]) && rs[i] != ':' && rs[i] != '"' {
+			i++
+		}
+		name := strings.ToLower(string(rs[start:i]))
+		if i+1 < len(rs) && rs[i] == ':' && !unicode.IsSpace(rs[i+1]) && qualifiers[name] {
+			t.qualifier = name
+			i++
+		} else {
+			i = start
+		}
+
+		if rs[i] == '"' {
+			i++
+			valueStart := i
+			for i < len(rs) && rs[i] != '"' {
+				i++
+			}
+			t.value = strings.TrimSpace(string(rs[valueStart:i]))
+			t.quoted = true
+			if i < len(rs) {
+				i++
+			}
+		} else {
+			valueStart := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) {
+				i++
+			}
+			t.value = strings.ReplaceAll(string(rs[valueStart:i]), `"`, "")
+		}
+
+		if t.value != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// HasText reports whether the query has words or phrases that must match
+func (q *Query) HasText() bool {
+	return len(q.Terms) > 0 || len(q.Phrases) > 0
+}
+
+// TextSearch renders the free text in MongoDB $text syntax. Quotes can't
+// appear inside values, so the output can't change the query's structure.
+func (q *Query) TextSearch() string {
+	parts := make([]string, 0, len(q.Terms)+len(q.Phrases)+len(q.ExcludedTerms)+len(q.ExcludedPhrases))
+	parts = append(parts, q.Terms...)
+	for _, p := range q.Phrases {
+		parts = append(parts, `"`+p+`"`)
+	}
+	for _, t := range q.ExcludedTerms {
+		parts = append(parts, "-"+t)
+	}
+	for _, p := range q.ExcludedPhrases {
+		parts = append(parts, `-"`+p+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// HighlightTerms are the lowercase words of the query's terms and phrases
+func (q *Query) HighlightTerms() []string {
+	var terms []string
+	for _, t := range q.Terms {
+		terms = append(terms, words(t)...)
+	}
+	for _, p := range q.Phrases {
+		terms = append(terms, words(p)...)
+	}
+	return terms
+}
@@ -0,0 +1,242 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) *time.Time {
+	d, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return &d
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		q    string
+		want Query
+	}{
+		{
+			name: "empty",
+			q:    "   ",
+			want: Query{},
+		},
+		{
+			name: "words",
+			q:    "todo  app",
+			want: Query{Terms: []string{"todo", "app"}},
+		},
+		{
+			name: "phrase and word",
+			q:    `"todo app" cli`,
+			want: Query{Terms: []string{"cli"}, Phrases: []string{"todo app"}},
+		},
+		{
+			name: "excluded word",
+			q:    "cli -electron",
+			want: Query{Terms: []string{"cli"}, ExcludedTerms: []string{"electron"}},
+		},
+		{
+			name: "excluded phrase",
+			q:    `cli -"web app"`,
+			want: Query{Terms: []string{"cli"}, ExcludedPhrases: []string{"web app"}},
+		},
+		{
+			name: "unterminated quote runs to the end",
+			q:    `cli "todo app  `,
+			want: Query{Terms: []string{"cli"}, Phrases: []string{"todo app"}},
+		},
+		{
+			name: "empty phrase is dropped",
+			q:    `"" cli`,
+			want: Query{Terms: []string{"cli"}},
+		},
+		{
+			name: "quotes inside a word are dropped",
+			q:    `say"hi"`,
+			want: Query{Terms: []string{"sayhi"}},
+		},
+		{
+			name: "lone dash is dropped",
+			q:    "- cli",
+			want: Query{Terms: []string{"cli"}},
+		},
+		{
+			name: "extra dashes are trimmed",
+			q:    "--electron",
+			want: Query{ExcludedTerms: []string{"electron"}},
+		},
+		{
+			name: "qualifiers",
+			q:    "tag:go -tag:js difficulty:Beginner -difficulty:advanced author:user_1 -author:user_2",
+			want: Query{
+				Tags:                 []string{"go"},
+				ExcludedTags:         []string{"js"},
+				Difficulties:         []string{"beginner"},
+				ExcludedDifficulties: []string{"advanced"},
+				Authors:              []string{"user_1"},
+				ExcludedAuthors:      []string{"user_2"},
+			},
+		},
+		{
+			name: "qualifier names are case insensitive",
+			q:    "TAG:Go",
+			want: Query{Tags: []string{"Go"}},
+		},
+		{
+			name: "quoted qualifier value",
+			q:    `tag:"machine learning" -tag:"web dev"`,
+			want: Query{Tags: []string{"machine learning"}, ExcludedTags: []string{"web dev"}},
+		},
+		{
+			name: "qualifier without a value is a word",
+			q:    "tag:",
+			want: Query{Terms: []string{"tag:"}},
+		},
+		{
+			name: "qualifier followed by a space is a word",
+			q:    "tag: go",
+			want: Query{Terms: []string{"tag:", "go"}},
+		},
+		{
+			name: "unknown qualifier is a word",
+			q:    "lang:go",
+			want: Query{Terms: []string{"lang:go"}},
+		},
+		{
+			name: "created on a day",
+			q:    "created:2025-01-31",
+			want: Query{CreatedFrom: date("2025-01-31"), CreatedTo: date("2025-02-01")},
+		},
+		{
+			name: "created after a day starts the next day",
+			q:    "created:>2025-01-31",
+			want: Query{CreatedFrom: date("2025-02-01")},
+		},
+		{
+			name: "created on or after a day",
+			q:    "created:>=2025-01-31",
+			want: Query{CreatedFrom: date("2025-01-31")},
+		},
+		{
+			name: "created before a day ends the previous day",
+			q:    "created:<2025-01-31",
+			want: Query{CreatedTo: date("2025-01-31")},
+		},
+		{
+			name: "created on or before a day includes it",
+			q:    "created:<=2025-01-31",
+			want: Query{CreatedTo: date("2025-02-01")},
+		},
+		{
+			name: "created range includes both days",
+			q:    "created:2025-01-01..2025-01-31",
+			want: Query{CreatedFrom: date("2025-01-01"), CreatedTo: date("2025-02-01")},
+		},
+		{
+			name: "created qualifiers narrow each other",
+			q:    "created:>=2025-01-01 created:>=2025-03-01 created:<2025-06-01 created:<=2025-04-30",
+			want: Query{CreatedFrom: date("2025-03-01"), CreatedTo: date("2025-05-01")},
+		},
+		{
+			name: "mixed",
+			q:    `"todo app" cli -electron tag:go difficulty:beginner created:>2025-01-01`,
+			want: Query{
+				Terms:         []string{"cli"},
+				Phrases:       []string{"todo app"},
+				ExcludedTerms: []string{"electron"},
+				Tags:          []string{"go"},
+				Difficulties:  []string{"beginner"},
+				CreatedFrom:   date("2025-01-02"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.q)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.q, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.q, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"created:yesterday",
+		"created:>2025-13-01",
+		"created:<=2025-1-31",
+		"created:2025-01-01..",
+		"created:..2025-01-31",
+		"created:2025-01-31T10:00",
+		"-created:2025-01-01",
+	}
+	for _, q := range tests {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("Parse(%q): want an error", q)
+		}
+	}
+}
+
+func TestTextSearch(t *testing.T) {
+	tests := []struct {
+		q    string
+		want string
+	}{
+		{"", ""},
+		{"tag:go created:>2025-01-01", ""},
+		{"todo app", "todo app"},
+		{`"todo app" cli -electron -"web app"`, `cli "todo app" -electron -"web app"`},
+		// Stray quotes can't open a phrase in the rendered search
+		{`cli"`, "cli"},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.q)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.q, err)
+		}
+		if got := q.TextSearch(); got != tt.want {
+			t.Errorf("TextSearch(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHasText(t *testing.T) {
+	tests := []struct {
+		q    string
+		want bool
+	}{
+		{"cli", true},
+		{`"todo app"`, true},
+		{"-electron", false},
+		{"tag:go", false},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.q)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.q, err)
+		}
+		if got := q.HasText(); got != tt.want {
+			t.Errorf("HasText(%q) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	q, err := Parse(`"Todo App" CLI-tool -electron tag:go`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"cli", "tool", "todo", "app"}
+	if got := q.HighlightTerms(); !reflect.DeepEqual(got, want) {
+		t.Errorf("HighlightTerms() = %v, want %v", got, want)
+	}
+}
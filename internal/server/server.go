@@ -1,13 +1,40 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"ikurotime/backlog-go-backend/config"
+	"ikurotime/backlog-go-backend/internal/ideas"
+	"ikurotime/backlog-go-backend/internal/lists"
 	"ikurotime/backlog-go-backend/internal/router"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+const (
+	// drainDelay gives load balancers time to observe the failing readiness probe
+	drainDelay      = 5 * time.Second
+	shutdownTimeout = 15 * time.Second
+	// publishInterval is how often scheduled drafts are checked for publishing
+	publishInterval = time.Minute
+	// purgeInterval is how often expired trash is permanently removed
+	purgeInterval = time.Hour
+	// matchInterval is how often new ideas are matched against saved searches
+	matchInterval = time.Minute
+	// profileSyncInterval is how often profiles are refreshed from Clerk
+	profileSyncInterval = time.Hour
+	// indexSyncInterval is how often the in-memory similar ideas and
+	// autocomplete indexes pick up changes made elsewhere
+	indexSyncInterval = time.Minute
+)
+
 type Server struct {
 	router *router.Router
 	client *mongo.Client
@@ -29,6 +56,47 @@ func NewServer(client *mongo.Client) (*Server, error) {
 	return s, nil
 }
 
+// Run serves HTTP on addr and runs background jobs until SIGINT or SIGTERM,
+// then marks the service as not ready and drains in-flight requests before returning
 func (s *Server) Run(addr string) error {
-	return s.router.GetEngine().Run(addr)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: s.router.GetEngine(),
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go ideas.NewMigrator(s.client).Run(ctx)
+	go ideas.NewPublishScheduler(s.client, publishInterval).Run(ctx)
+	go ideas.NewPurger(s.client, purgeInterval, lists.RemoveIdea).Run(ctx)
+	go ideas.NewSavedSearchMatcher(s.client, matchInterval).Run(ctx)
+	go ideas.NewProfileSyncer(s.client, profileSyncInterval).Run(ctx)
+	go s.router.GetIdeas().SyncIndexes(ctx, indexSyncInterval)
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Print("Shutting down server...")
+	s.router.GetHealth().SetShuttingDown()
+	time.Sleep(drainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	return <-errCh
 }
@@ -0,0 +1,291 @@
+package similarity
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Weights of the signals combined into an Index score
+const (
+	textWeight       = 0.6
+	tagWeight        = 0.3
+	difficultyWeight = 0.1
+)
+
+// Document is the content of an idea that the Index compares
+type Document struct {
+	Title       string
+	Description string
+	Tags        []string
+	Difficulty  string
+}
+
+// Match is a related document and how related it is, from 0 to 1
+type Match struct {
+	ID    string
+	Score float64
+}
+
+type entry struct {
+	tf         map[string]float64
+	tags       Set
+	difficulty string
+
+	// norm is the length of the TF-IDF vector, valid while normVersion
+	// matches the index version
+	norm        float64
+	normVersion uint64
+}
+
+// Index is an in-memory TF-IDF index over title and description, combined
+// with tag overlap and matching difficulty. It is updated one document at a
+// time and caches the top matches per document until a change touches them.
+type Index struct {
+	mu sync.Mutex
+
+	topN        int
+	docs        map[string]*entry
+	df          map[string]int
+	postings    map[string]map[string]struct{}
+	tagPostings map[string]map[string]struct{}
+	cache       map[string][]Match
+
+	// version changes whenever document frequencies do
+	version uint64
+}
+
+// NewIndex creates an empty index that keeps the topN matches per document
+func NewIndex(topN int) *Index {
+	return &Index{
+		topN:        topN,
+		docs:        map[string]*entry{},
+		df:          map[string]int{},
+		postings:    map[string]map[string]struct{}{},
+		tagPostings: map[string]map[string]struct{}{},
+		cache:       map[string][]Match{},
+	}
+}
+
+// newEntry computes term frequencies, counting title words twice
+func newEntry(d Document) *entry {
+	e := &entry{
+		tf:         map[string]float64{},
+		tags:       Set{},
+		difficulty: d.Difficulty,
+	}
+	for _, t := range Tokenize(d.Title) {
+		e.tf[t] += 2
+	}
+	for _, t := range Tokenize(d.Description) {
+		e.tf[t]++
+	}
+	for term, n := range e.tf {
+		e.tf[term] = 1 + math.Log(n)
+	}
+	for _, tag := range d.Tags {
+		e.tags[tag] = struct{}{}
+	}
+	return e
+}
+
+// Len is the number of indexed documents
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.docs)
+}
+
+// IDs returns the ids of every indexed document
+func (ix *Index) IDs() []string {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ids := make([]string, 0, len(ix.docs))
+	for id := range ix.docs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Upsert adds or replaces the document with id
+func (ix *Index) Upsert(id string, d Document) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	e := newEntry(d)
+	ix.docs[id] = e
+	for term := range e.tf {
+		ix.df[term]++
+		addPosting(ix.postings, term, id)
+	}
+	for tag := range e.tags {
+		addPosting(ix.tagPostings, tag, id)
+	}
+	ix.version++
+	ix.invalidate(id, e)
+}
+
+// Remove drops the document with id, if indexed
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.remove(id) {
+		ix.version++
+	}
+}
+
+func (ix *Index) remove(id string) bool {
+	e, ok := ix.docs[id]
+	if !ok {
+		return false
+	}
+	ix.invalidate(id, e)
+	delete(ix.docs, id)
+	for term := range e.tf {
+		if ix.df[term]--; ix.df[term] == 0 {
+			delete(ix.df, term)
+		}
+		removePosting(ix.postings, term, id)
+	}
+	for tag := range e.tags {
+		removePosting(ix.tagPostings, tag, id)
+	}
+	return true
+}
+
+// invalidate drops the cached matches of id and of every document sharing a
+// term or tag with e, since only their scores against id can change
+func (ix *Index) invalidate(id string, e *entry) {
+	delete(ix.cache, id)
+	for term := range e.tf {
+		for other := range ix.postings[term] {
+			delete(ix.cache, other)
+		}
+	}
+	for tag := range e.tags {
+		for other := range ix.tagPostings[tag] {
+			delete(ix.cache, other)
+		}
+	}
+}
+
+// Similar returns the documents most related to the indexed document id, best
+// first. ok is false when id isn't indexed.
+func (ix *Index) Similar(id string) (matches []Match, ok bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	e, ok := ix.docs[id]
+	if !ok {
+		return nil, false
+	}
+	if cached, ok := ix.cache[id]; ok {
+		return cached, true
+	}
+
+	matches = ix.rank(id, e)
+	ix.cache[id] = matches
+	return matches, true
+}
+
+// SimilarTo ranks indexed documents against a document that isn't indexed,
+// such as a draft. Results aren't cached.
+func (ix *Index) SimilarTo(d Document) []Match {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	return ix.rank("", newEntry(d))
+}
+
+// rank scores every document sharing a term or tag with e, except id
+func (ix *Index) rank(id string, e *entry) []Match {
+	candidates := map[string]struct{}{}
+	for term := range e.tf {
+		for other := range ix.postings[term] {
+			candidates[other] = struct{}{}
+		}
+	}
+	for tag := range e.tags {
+		for other := range ix.tagPostings[tag] {
+			candidates[other] = struct{}{}
+		}
+	}
+	delete(candidates, id)
+
+	norm := ix.norm(e)
+	matches := make([]Match, 0, len(candidates))
+	for other := range candidates {
+		o := ix.docs[other]
+
+		var cosine float64
+		if otherNorm := ix.norm(o); norm > 0 && otherNorm > 0 {
+			var dot float64
+			for term, w := range e.tf {
+				if ow, ok := o.tf[term]; ok {
+					idf := ix.idf(term)
+					dot += w * idf * ow * idf
+				}
+			}
+			cosine = dot / (norm * otherNorm)
+		}
+
+		score := textWeight*cosine + tagWeight*Jaccard(e.tags, o.tags)
+		if e.difficulty != "" && e.difficulty == o.difficulty {
+			score += difficultyWeight
+		}
+		matches = append(matches, Match{ID: other, Score: score})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	if len(matches) > ix.topN {
+		matches = matches[:ix.topN]
+	}
+	return matches
+}
+
+// idf is the smoothed inverse document frequency of term
+func (ix *Index) idf(term string) float64 {
+	return math.Log(1 + float64(len(ix.docs))/float64(ix.df[term]+1))
+}
+
+// norm returns the length of e's TF-IDF vector, recomputing it when document
+// frequencies have changed
+func (ix *Index) norm(e *entry) float64 {
+	if e.normVersion == ix.version && e.norm != 0 {
+		return e.norm
+	}
+	var sum float64
+	for term, w := range e.tf {
+		x := w * ix.idf(term)
+		sum += x * x
+	}
+	e.norm = math.Sqrt(sum)
+	e.normVersion = ix.version
+	return e.norm
+}
+
+func addPosting(postings map[string]map[string]struct{}, key, id string) {
+	ids, ok := postings[key]
+	if !ok {
+		ids = map[string]struct{}{}
+		postings[key] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removePosting(postings map[string]map[string]struct{}, key, id string) {
+	ids := postings[key]
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(postings, key)
+	}
+}
@@ -0,0 +1,245 @@
+package similarity
+
+import (
+	"math"
+	"testing"
+)
+
+func testIndex() *Index {
+	ix := NewIndex(10)
+	ix.Upsert("todo", Document{
+		Title:       "Todo list app",
+		Description: "Track tasks with due dates and reminders",
+		Tags:        []string{"react", "productivity"},
+		Difficulty:  "beginner",
+	})
+	ix.Upsert("kanban", Document{
+		Title:       "Kanban board",
+		Description: "Drag tasks between columns to track progress",
+		Tags:        []string{"react", "productivity"},
+		Difficulty:  "intermediate",
+	})
+	ix.Upsert("weather", Document{
+		Title:       "Weather dashboard",
+		Description: "Show forecasts from a public API",
+		Tags:        []string{"api"},
+		Difficulty:  "beginner",
+	})
+	ix.Upsert("compiler", Document{
+		Title:       "Toy compiler",
+		Description: "Parse and compile a small language to bytecode",
+		Tags:        []string{"rust"},
+		Difficulty:  "advanced",
+	})
+	return ix
+}
+
+func matchIDs(matches []Match) []string {
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func contains(ids []string, id string) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestIndexSimilarRanksSharedTermsAndTags(t *testing.T) {
+	ix := testIndex()
+
+	matches, ok := ix.Similar("todo")
+	if !ok {
+		t.Fatal("Similar(todo): not indexed")
+	}
+	if len(matches) == 0 || matches[0].ID != "kanban" {
+		t.Fatalf("Similar(todo) = %v, want kanban first", matchIDs(matches))
+	}
+	for _, m := range matches {
+		if m.ID == "todo" {
+			t.Errorf("Similar(todo) includes itself")
+		}
+		if m.Score < 0 || m.Score > 1 {
+			t.Errorf("score of %s = %v, want within [0, 1]", m.ID, m.Score)
+		}
+	}
+	for i := 1; i < len(matches); i++ {
+		if matches[i].Score > matches[i-1].Score {
+			t.Errorf("matches not sorted by score: %v", matches)
+		}
+	}
+	// The compiler shares no term, tag or difficulty with the todo app
+	if contains(matchIDs(matches), "compiler") {
+		t.Errorf("Similar(todo) = %v, want no unrelated compiler", matchIDs(matches))
+	}
+
+	if _, ok := ix.Similar("missing"); ok {
+		t.Error("Similar(missing): ok = true, want false")
+	}
+}
+
+func TestIndexTopN(t *testing.T) {
+	ix := NewIndex(2)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		ix.Upsert(id, Document{Title: "Chess engine " + id, Tags: []string{"chess"}})
+	}
+
+	matches, _ := ix.Similar("a")
+	if len(matches) != 2 {
+		t.Fatalf("Similar(a) returned %d matches, want topN 2", len(matches))
+	}
+	// Equal scores are ordered by ID
+	if got := matchIDs(matches); got[0] != "b" || got[1] != "c" {
+		t.Errorf("Similar(a) = %v, want [b c]", got)
+	}
+}
+
+func TestIndexUpsertInvalidatesNeighbours(t *testing.T) {
+	ix := testIndex()
+
+	// Fill the caches of every document
+	for _, id := range []string{"todo", "kanban", "weather", "compiler"} {
+		ix.Similar(id)
+	}
+
+	ix.Upsert("habits", Document{
+		Title:       "Habit tracker",
+		Description: "Track daily habits and streaks",
+		Tags:        []string{"productivity"},
+	})
+
+	// Documents sharing a term or tag with the new one get new matches
+	for _, id := range []string{"todo", "kanban"} {
+		if _, cached := ix.cache[id]; cached {
+			t.Errorf("cache of %s kept after a related document was added", id)
+		}
+		matches, _ := ix.Similar(id)
+		if !contains(matchIDs(matches), "habits") {
+			t.Errorf("Similar(%s) = %v, want habits", id, matchIDs(matches))
+		}
+	}
+	// Unrelated documents keep their cache
+	if _, cached := ix.cache["compiler"]; !cached {
+		t.Error("cache of compiler dropped by an unrelated upsert")
+	}
+}
+
+func TestIndexUpsertReplacesDocument(t *testing.T) {
+	ix := testIndex()
+	ix.Similar("weather")
+
+	// The todo app is rewritten into a weather app
+	ix.Upsert("todo", Document{
+		Title:       "Weather alerts",
+		Description: "Push forecasts from a public API",
+		Tags:        []string{"api"},
+		Difficulty:  "beginner",
+	})
+
+	if ix.Len() != 4 {
+		t.Errorf("Len() = %d after replacing a document, want 4", ix.Len())
+	}
+	matches, _ := ix.Similar("weather")
+	if len(matches) == 0 || matches[0].ID != "todo" {
+		t.Errorf("Similar(weather) = %v, want the rewritten todo first", matchIDs(matches))
+	}
+
+	// Terms only the old version had are gone
+	if _, ok := ix.postings["reminders"]; ok {
+		t.Error("postings still hold a term of the replaced document")
+	}
+	if _, ok := ix.df["reminders"]; ok {
+		t.Error("document frequencies still count a term of the replaced document")
+	}
+}
+
+func TestIndexRemoveInvalidatesNeighbours(t *testing.T) {
+	ix := testIndex()
+	ix.Similar("todo")
+	ix.Similar("compiler")
+
+	ix.Remove("kanban")
+
+	if _, cached := ix.cache["todo"]; cached {
+		t.Error("cache of todo kept after its neighbour kanban was removed")
+	}
+	matches, _ := ix.Similar("todo")
+	if contains(matchIDs(matches), "kanban") {
+		t.Errorf("Similar(todo) = %v after removing kanban", matchIDs(matches))
+	}
+	if _, cached := ix.cache["compiler"]; !cached {
+		t.Error("cache of compiler dropped by removing an unrelated document")
+	}
+	if _, ok := ix.Similar("kanban"); ok {
+		t.Error("Similar(kanban): ok = true after Remove")
+	}
+	if len(ix.tagPostings["react"]) != 1 {
+		t.Errorf("react tag postings = %v, want only todo", ix.tagPostings["react"])
+	}
+
+	// Removing an unknown document changes nothing
+	version := ix.version
+	ix.Remove("missing")
+	if ix.version != version {
+		t.Error("removing an unknown document bumped the index version")
+	}
+}
+
+func TestIndexNormFollowsDocumentFrequencies(t *testing.T) {
+	ix := testIndex()
+	e := ix.docs["todo"]
+	before := ix.norm(e)
+	if e.normVersion != ix.version {
+		t.Fatal("norm not cached at the current version")
+	}
+
+	// A document sharing terms with todo lowers their idf, so todo's norm must
+	// be recomputed rather than served from the cache
+	ix.Upsert("todo-2", Document{Title: "Todo list app", Description: "Track tasks with due dates and reminders"})
+	after := ix.norm(e)
+	if after == before {
+		t.Error("norm unchanged after document frequencies changed")
+	}
+
+	var want float64
+	for term, w := range e.tf {
+		x := w * ix.idf(term)
+		want += x * x
+	}
+	if math.Abs(after-math.Sqrt(want)) > 1e-9 {
+		t.Errorf("norm = %v, want %v", after, math.Sqrt(want))
+	}
+}
+
+func TestIndexSimilarTo(t *testing.T) {
+	ix := testIndex()
+
+	matches := ix.SimilarTo(Document{Title: "Compiler for a toy language", Tags: []string{"rust"}})
+	if len(matches) == 0 || matches[0].ID != "compiler" {
+		t.Errorf("SimilarTo(draft) = %v, want compiler first", matchIDs(matches))
+	}
+	if ix.Len() != 4 {
+		t.Errorf("SimilarTo indexed the draft: Len() = %d", ix.Len())
+	}
+}
+
+func TestIndexIDs(t *testing.T) {
+	ix := testIndex()
+	ix.Remove("weather")
+
+	ids := ix.IDs()
+	if len(ids) != 3 {
+		t.Fatalf("IDs() = %v, want 3 ids", ids)
+	}
+	for _, id := range []string{"todo", "kanban", "compiler"} {
+		if !contains(ids, id) {
+			t.Errorf("IDs() = %v, missing %s", ids, id)
+		}
+	}
+}
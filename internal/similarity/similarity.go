@@ -0,0 +1,90 @@
+// Package similarity scores how alike two pieces of idea text are
+package similarity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are common English words that carry no meaning for similarity
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "how": true, "in": true,
+	"is": true, "it": true, "its": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "what": true,
+	"when": true, "where": true, "which": true, "with": true, "you": true, "your": true,
+}
+
+// Set is a set of terms or shingles
+type Set map[string]struct{}
+
+// Tokenize lowercases text and splits it into words, dropping punctuation and stop words
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	tokens := fields[:0]
+	for _, f := range fields {
+		if !stopWords[f] {
+			tokens = append(tokens, f)
+		}
+	}
+	return tokens
+}
+
+// Shingles returns the set of k-word shingles in tokens. Texts shorter than k
+// words become a single shingle.
+func Shingles(tokens []string, k int) Set {
+	set := Set{}
+	if len(tokens) == 0 {
+		return set
+	}
+	if len(tokens) < k {
+		set[strings.Join(tokens, " ")] = struct{}{}
+		return set
+	}
+	for i := 0; i+k <= len(tokens); i++ {
+		set[strings.Join(tokens[i:i+k], " ")] = struct{}{}
+	}
+	return set
+}
+
+// Jaccard is the size of the intersection of a and b over the size of their union
+func Jaccard(a, b Set) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for s := range a {
+		if _, ok := b[s]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Fingerprint is the precomputed shingles of an idea's title and description
+type Fingerprint struct {
+	title Set
+	body  Set
+}
+
+// NewFingerprint shingles an idea's title by word and its title plus
+// description by word pairs
+func NewFingerprint(title, description string) Fingerprint {
+	titleTokens := Tokenize(title)
+	bodyTokens := append(append([]string{}, titleTokens...), Tokenize(description)...)
+	return Fingerprint{
+		title: Shingles(titleTokens, 1),
+		body:  Shingles(bodyTokens, 2),
+	}
+}
+
+// Score rates how alike two ideas are from 0 to 1. Titles weigh as much as
+// the whole text, since near-duplicates usually share most of their title.
+func (f Fingerprint) Score(other Fingerprint) float64 {
+	return (Jaccard(f.title, other.title) + Jaccard(f.body, other.body)) / 2
+}
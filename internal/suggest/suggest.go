@@ -0,0 +1,271 @@
+// Package suggest is an in-memory autocomplete index of idea titles and tags
+// that tolerates typos
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// typoPenalty is how much each edit costs against popularity when ranking
+const typoPenalty = 2.0
+
+// IdeaSuggestion is an idea whose title matches the query
+type IdeaSuggestion struct {
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	LikesCount int     `json:"likes_count"`
+	Score      float64 `json:"score"`
+}
+
+// TagSuggestion is a tag matching the query, with how many ideas have it
+type TagSuggestion struct {
+	Tag        string  `json:"tag"`
+	IdeasCount int     `json:"ideas_count"`
+	Score      float64 `json:"score"`
+}
+
+type idea struct {
+	title string
+	words []string
+	tags  []string
+	likes int
+}
+
+type tagStats struct {
+	name  string
+	ideas int
+	likes int
+}
+
+// Index suggests idea titles by the words they contain and tags by their
+// name. The last query word matches as a prefix, the others as whole words,
+// all within a few typos.
+type Index struct {
+	mu sync.RWMutex
+
+	ideas map[string]*idea
+	words *trie
+	tags  *trie
+	// tagStats is keyed by lowercased tag
+	tagStats map[string]*tagStats
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		ideas:    map[string]*idea{},
+		words:    newTrie(),
+		tags:     newTrie(),
+		tagStats: map[string]*tagStats{},
+	}
+}
+
+// normalize lowercases text and splits it into words
+func normalize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// maxEdits is how many typos a query word of n runes may contain
+func maxEdits(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Upsert adds or replaces the idea with id
+func (ix *Index) Upsert(id, title string, tags []string, likes int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+
+	i := &idea{title: title, words: normalize(title), likes: likes}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		key := strings.ToLower(strings.TrimSpace(tag))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		i.tags = append(i.tags, key)
+
+		stats, ok := ix.tagStats[key]
+		if !ok {
+			stats = &tagStats{name: tag}
+			ix.tagStats[key] = stats
+			ix.tags.add(key, key)
+		}
+		stats.ideas++
+		stats.likes += likes
+	}
+	for _, w := range i.words {
+		ix.words.add(w, id)
+	}
+	ix.ideas[id] = i
+}
+
+// Remove drops the idea with id, if indexed
+func (ix *Index) Remove(id string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id string) {
+	i, ok := ix.ideas[id]
+	if !ok {
+		return
+	}
+	delete(ix.ideas, id)
+	for _, w := range i.words {
+		ix.words.remove(w, id)
+	}
+	for _, key := range i.tags {
+		stats := ix.tagStats[key]
+		stats.ideas--
+		stats.likes -= i.likes
+		if stats.ideas == 0 {
+			delete(ix.tagStats, key)
+			ix.tags.remove(key, key)
+		}
+	}
+}
+
+// SetLikes updates the popularity of an indexed idea
+func (ix *Index) SetLikes(id string, likes int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	i, ok := ix.ideas[id]
+	if !ok || i.likes == likes {
+		return
+	}
+	for _, key := range i.tags {
+		ix.tagStats[key].likes += likes - i.likes
+	}
+	i.likes = likes
+}
+
+// Len is the number of indexed ideas
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.ideas)
+}
+
+// popularity turns a like count into a score that grows slowly
+func popularity(likes int) float64 {
+	return math.Log1p(math.Max(float64(likes), 0))
+}
+
+// Ideas returns up to limit ideas whose titles contain every word of query,
+// the last one as a prefix, best first
+func (ix *Index) Ideas(query string, limit int) []IdeaSuggestion {
+	words := normalize(query)
+	suggestions := []IdeaSuggestion{}
+	if len(words) == 0 {
+		return suggestions
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	// edits holds the fewest typos each candidate needed for the words so far
+	var edits map[string]int
+	for n, w := range words {
+		last := n == len(words)-1
+		matched := map[string]int{}
+		ix.words.search(w, maxEdits(len([]rune(w))), last, func(id string, dist int) {
+			if d, ok := matched[id]; !ok || dist < d {
+				matched[id] = dist
+			}
+		})
+		if edits == nil {
+			edits = matched
+			continue
+		}
+		for id, d := range edits {
+			if dist, ok := matched[id]; ok {
+				edits[id] = d + dist
+			} else {
+				delete(edits, id)
+			}
+		}
+	}
+
+	lowered := strings.ToLower(strings.TrimSpace(query))
+	for id, dist := range edits {
+		i := ix.ideas[id]
+		score := popularity(i.likes) - typoPenalty*float64(dist)
+		if strings.HasPrefix(strings.ToLower(i.title), lowered) {
+			score++
+		}
+		suggestions = append(suggestions, IdeaSuggestion{ID: id, Title: i.title, LikesCount: i.likes, Score: score})
+	}
+	sort.Slice(suggestions, func(a, b int) bool {
+		if suggestions[a].Score != suggestions[b].Score {
+			return suggestions[a].Score > suggestions[b].Score
+		}
+		return suggestions[a].Title < suggestions[b].Title
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// Tags returns up to limit tags starting with query, best first
+func (ix *Index) Tags(query string, limit int) []TagSuggestion {
+	key := strings.ToLower(strings.TrimSpace(query))
+	suggestions := []TagSuggestion{}
+	if key == "" {
+		return suggestions
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	edits := map[string]int{}
+	ix.tags.search(key, maxEdits(len([]rune(key))), true, func(tag string, dist int) {
+		if d, ok := edits[tag]; !ok || dist < d {
+			edits[tag] = dist
+		}
+	})
+	for tag, dist := range edits {
+		stats := ix.tagStats[tag]
+		score := popularity(stats.likes) - typoPenalty*float64(dist)
+		if tag == key {
+			score++
+		}
+		suggestions = append(suggestions, TagSuggestion{
+			Tag:        stats.name,
+			IdeasCount: stats.ideas,
+			Score:      score,
+		})
+	}
+	sort.Slice(suggestions, func(a, b int) bool {
+		if suggestions[a].Score != suggestions[b].Score {
+			return suggestions[a].Score > suggestions[b].Score
+		}
+		if suggestions[a].IdeasCount != suggestions[b].IdeasCount {
+			return suggestions[a].IdeasCount > suggestions[b].IdeasCount
+		}
+		return suggestions[a].Tag < suggestions[b].Tag
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
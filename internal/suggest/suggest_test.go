@@ -0,0 +1,206 @@
+package suggest
+
+import (
+	"reflect"
+	"testing"
+)
+
+// catalog is indexed by the tests that don't build their own index
+var catalog = []struct {
+	id, title string
+	tags      []string
+	likes     int
+}{
+	{"react-todo", "React todo app", []string{"React", "frontend"}, 10},
+	{"vue-todo", "Vue todo app", []string{"vue", "frontend"}, 3},
+	{"dashboard", "React dashboard", []string{"react", "charts"}, 0},
+	{"compiler", "Toy compiler in Go", []string{"go", "compilers"}, 7},
+}
+
+func catalogIndex() *Index {
+	ix := NewIndex()
+	for _, e := range catalog {
+		ix.Upsert(e.id, e.title, e.tags, e.likes)
+	}
+	return ix
+}
+
+func suggestedIDs(suggestions []IdeaSuggestion) []string {
+	ids := []string{}
+	for _, s := range suggestions {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func tagNames(suggestions []TagSuggestion) []string {
+	tags := []string{}
+	for _, s := range suggestions {
+		tags = append(tags, s.Tag)
+	}
+	return tags
+}
+
+func TestIdeas(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty query", "  ", []string{}},
+		{"prefix of the last word", "rea", []string{"react-todo", "dashboard"}},
+		{"case insensitive", "REACT Dash", []string{"dashboard"}},
+		{"every word must match", "react todo", []string{"react-todo"}},
+		{"words in any order", "app todo", []string{"react-todo", "vue-todo"}},
+		{"earlier words match whole words only", "tod app", []string{}},
+		{"short words allow no typos", "tdo", []string{}},
+		{"four runes allow one typo", "tido", []string{"react-todo", "vue-todo"}},
+		{"one typo", "reakt", []string{"react-todo", "dashboard"}},
+		{"one typo in a prefix", "compli", []string{"compiler"}},
+		{"two typos in long words", "dashbaord", []string{"dashboard"}},
+		{"too many typos", "rezkt", []string{}},
+		{"no match", "kotlin", []string{}},
+	}
+
+	ix := catalogIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestedIDs(ix.Ideas(tt.query, 10)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ideas(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdeasLimit(t *testing.T) {
+	ix := catalogIndex()
+	if got := suggestedIDs(ix.Ideas("app", 1)); !reflect.DeepEqual(got, []string{"react-todo"}) {
+		t.Errorf("Ideas(app, 1) = %v, want only the most liked", got)
+	}
+}
+
+func TestIdeasRanking(t *testing.T) {
+	ix := NewIndex()
+	ix.Upsert("list", "Todo list", nil, 0)
+	ix.Upsert("popular", "Todo app", nil, 50)
+
+	if got := suggestedIDs(ix.Ideas("todo", 10)); !reflect.DeepEqual(got, []string{"popular", "list"}) {
+		t.Errorf("Ideas(todo) = %v, want the most liked first", got)
+	}
+
+	ix.SetLikes("list", 500)
+	if got := suggestedIDs(ix.Ideas("todo", 10)); !reflect.DeepEqual(got, []string{"list", "popular"}) {
+		t.Errorf("Ideas(todo) after SetLikes = %v, want list first", got)
+	}
+
+	// A typo costs more than a few likes
+	ix = NewIndex()
+	ix.Upsert("exact", "Reach", nil, 0)
+	ix.Upsert("typo", "React", nil, 5)
+	if got := suggestedIDs(ix.Ideas("reach", 10)); !reflect.DeepEqual(got, []string{"exact", "typo"}) {
+		t.Errorf("Ideas(reach) = %v, want the exact match first", got)
+	}
+}
+
+func TestRemoveAndReupsert(t *testing.T) {
+	ix := catalogIndex()
+
+	ix.Remove("react-todo")
+	if ix.Len() != 3 {
+		t.Errorf("Len() = %d after Remove, want 3", ix.Len())
+	}
+	if got := suggestedIDs(ix.Ideas("react", 10)); !reflect.DeepEqual(got, []string{"dashboard"}) {
+		t.Errorf("Ideas(react) after Remove = %v, want [dashboard]", got)
+	}
+	// Removing twice or removing an unknown idea is harmless
+	ix.Remove("react-todo")
+	ix.Remove("missing")
+
+	ix.Upsert("dashboard", "Svelte dashboard", []string{"svelte"}, 2)
+	if got := suggestedIDs(ix.Ideas("react", 10)); len(got) != 0 {
+		t.Errorf("Ideas(react) after re-upsert = %v, want none", got)
+	}
+	if got := suggestedIDs(ix.Ideas("svelte", 10)); !reflect.DeepEqual(got, []string{"dashboard"}) {
+		t.Errorf("Ideas(svelte) after re-upsert = %v, want [dashboard]", got)
+	}
+	if got := tagNames(ix.Tags("react", 10)); len(got) != 0 {
+		t.Errorf("Tags(react) = %v, want none once no idea has it", got)
+	}
+	if _, ok := ix.tags.root.children['r']; ok {
+		t.Error("trie keeps nodes of removed tags")
+	}
+}
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"empty query", "", []string{}},
+		{"prefix", "fro", []string{"frontend"}},
+		{"keeps the first spelling", "react", []string{"React"}},
+		{"exact match first", "go", []string{"go"}},
+		{"one typo", "compilrs", []string{"compilers"}},
+		{"no match", "kotlin", []string{}},
+	}
+
+	ix := catalogIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tagNames(ix.Tags(tt.query, 10)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tags(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagStats(t *testing.T) {
+	ix := NewIndex()
+	ix.Upsert("a", "Alpha", []string{"Go", "go", "cli"}, 4)
+	ix.Upsert("b", "Beta", []string{"go"}, 6)
+
+	stats := ix.tagStats["go"]
+	if stats.ideas != 2 || stats.likes != 10 {
+		t.Fatalf("go stats = %+v, want 2 ideas and 10 likes", *stats)
+	}
+
+	ix.SetLikes("a", 1)
+	if stats.likes != 7 || ix.tagStats["cli"].likes != 1 {
+		t.Errorf("likes after SetLikes = go %d, cli %d, want 7 and 1", stats.likes, ix.tagStats["cli"].likes)
+	}
+	// Unknown ideas are ignored
+	ix.SetLikes("missing", 100)
+
+	ix.Upsert("b", "Beta", []string{"go"}, 2)
+	if stats.ideas != 2 || stats.likes != 3 {
+		t.Errorf("go stats after re-upsert = %+v, want 2 ideas and 3 likes", *stats)
+	}
+
+	ix.Remove("a")
+	if stats.ideas != 1 || stats.likes != 2 {
+		t.Errorf("go stats after Remove = %+v, want 1 idea and 2 likes", *stats)
+	}
+	if _, ok := ix.tagStats["cli"]; ok {
+		t.Error("cli stats kept after its only idea was removed")
+	}
+
+	got := ix.Tags("go", 10)
+	if len(got) != 1 || got[0].IdeasCount != 1 {
+		t.Errorf("Tags(go) = %+v, want go with 1 idea", got)
+	}
+}
+
+func TestTagsRankByLikes(t *testing.T) {
+	ix := NewIndex()
+	ix.Upsert("a", "Alpha", []string{"golang"}, 0)
+	ix.Upsert("b", "Beta", []string{"gopher"}, 40)
+
+	if got := tagNames(ix.Tags("go", 10)); !reflect.DeepEqual(got, []string{"gopher", "golang"}) {
+		t.Errorf("Tags(go) = %v, want the most liked first", got)
+	}
+	ix.SetLikes("a", 400)
+	if got := tagNames(ix.Tags("go", 10)); !reflect.DeepEqual(got, []string{"golang", "gopher"}) {
+		t.Errorf("Tags(go) after SetLikes = %v, want golang first", got)
+	}
+}
@@ -0,0 +1,100 @@
+package suggest
+
+// trie maps words to the values, such as idea IDs, stored under them
+type trie struct {
+	root *node
+}
+
+type node struct {
+	children map[rune]*node
+	values   map[string]struct{}
+}
+
+func newTrie() *trie {
+	return &trie{root: &node{}}
+}
+
+func (t *trie) add(word, value string) {
+	n := t.root
+	for _, r := range word {
+		if n.children == nil {
+			n.children = map[rune]*node{}
+		}
+		child, ok := n.children[r]
+		if !ok {
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+	}
+	if n.values == nil {
+		n.values = map[string]struct{}{}
+	}
+	n.values[value] = struct{}{}
+}
+
+// remove drops value from word and prunes the nodes left empty
+func (t *trie) remove(word, value string) {
+	removeFrom(t.root, []rune(word), value)
+}
+
+// removeFrom reports whether n became empty
+func removeFrom(n *node, word []rune, value string) bool {
+	if len(word) == 0 {
+		delete(n.values, value)
+	} else if child, ok := n.children[word[0]]; ok && removeFrom(child, word[1:], value) {
+		delete(n.children, word[0])
+	}
+	return len(n.values) == 0 && len(n.children) == 0
+}
+
+// search visits the values of words within maxEdits edits of word, or when
+// prefix is set, of words starting within maxEdits edits of it. dist is the
+// edit distance of the best match for the value's word.
+//
+// It walks the trie computing one row of the Levenshtein matrix per node and
+// skips subtrees that can no longer come within maxEdits.
+func (t *trie) search(word string, maxEdits int, prefix bool, visit func(value string, dist int)) {
+	query := []rune(word)
+	row := make([]int, len(query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	best := len(query)
+	for r, child := range t.root.children {
+		searchNode(child, r, query, row, best, maxEdits, prefix, visit)
+	}
+}
+
+func searchNode(n *node, r rune, query []rune, prev []int, best, maxEdits int, prefix bool, visit func(string, int)) {
+	row := make([]int, len(prev))
+	row[0] = prev[0] + 1
+	rowMin := row[0]
+	for j := 1; j < len(row); j++ {
+		cost := 1
+		if query[j-1] == r {
+			cost = 0
+		}
+		row[j] = min(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+		rowMin = min(rowMin, row[j])
+	}
+
+	dist := row[len(query)]
+	if prefix {
+		// Every word below a prefix that matched is a completion
+		best = min(best, dist)
+		dist = best
+	}
+	if len(n.values) > 0 && dist <= maxEdits {
+		for value := range n.values {
+			visit(value, dist)
+		}
+	}
+
+	if rowMin > maxEdits && (!prefix || best > maxEdits) {
+		return
+	}
+	for childRune, child := range n.children {
+		searchNode(child, childRune, query, row, best, maxEdits, prefix, visit)
+	}
+}
@@ -5,11 +5,14 @@ import (
 	"ikurotime/backlog-go-backend/config"
 	"log"
 
+	"go.mongodb.org/mongo-driver/v2/event"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func ConnectMongoDB() *mongo.Client {
+// ConnectMongoDB connects to the configured MongoDB, reporting every command
+// to monitor
+func ConnectMongoDB(monitor *event.CommandMonitor) *mongo.Client {
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		log.Fatal(err)
@@ -17,7 +20,7 @@ func ConnectMongoDB() *mongo.Client {
 	uri := cfg.MongoDBConfig.Protocol + "://" + cfg.MongoDBConfig.User + ":" + cfg.MongoDBConfig.Password + "@" + cfg.MongoDBConfig.Host + ":" + cfg.MongoDBConfig.Port + "/?directConnection=true"
 	log.Print(uri)
 
-	clientOptions := options.Client().ApplyURI(uri)
+	clientOptions := options.Client().ApplyURI(uri).SetMonitor(monitor)
 	client, err := mongo.Connect(clientOptions)
 	if err != nil {
 		log.Fatal(err)
@@ -30,3 +33,30 @@ func ConnectMongoDB() *mongo.Client {
 	log.Print("[ --- Connected to MongoDB --- ]")
 	return client
 }
+
+// ChainMonitors fans command events out to every monitor in order
+func ChainMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}